@@ -0,0 +1,11 @@
+# Import by identifier in the provider space
+terraform import elastic-siem-detection_detection_rule.my_rules 00000000-0000-0000-0000-000000000000
+
+# Import by identifier in a specific space
+terraform import elastic-siem-detection_detection_rule.my_rules security-ops/00000000-0000-0000-0000-000000000000
+
+# Import by rule_id, which does not change across clusters and spaces
+terraform import elastic-siem-detection_detection_rule.my_rules rule_id:hacker_rule
+
+# Import by rule_id in a specific space
+terraform import elastic-siem-detection_detection_rule.my_rules security-ops/rule_id:hacker_rule
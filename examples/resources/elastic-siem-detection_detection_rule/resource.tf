@@ -57,3 +57,78 @@ resource "elastic-siem-detection_detection_rule" "my_rules" {
   # Helps syncing between objects
   depends_on = [elastic-siem_exception_container.my_containers]
 }
+
+# The same kind of rule described with typed attributes and blocks,
+# rule_content holds the fields which have no typed attribute
+resource "elastic-siem-detection_detection_rule" "typed_rule" {
+  name        = "Rule to catch a hacker"
+  description = "This rule catches a hacker."
+  type        = "query"
+  language    = "kuery"
+  query       = "user.name: \"hacker\""
+  index       = ["myindex-*"]
+  severity    = "low"
+  risk_score  = 21
+  tags        = ["MyTag"]
+
+  threat {
+    framework = "MITRE ATT&CK"
+    tactic {
+      id        = "TA0001"
+      name      = "Initial Access"
+      reference = "https://attack.mitre.org/tactics/TA0001/"
+    }
+    technique {
+      id        = "T1133"
+      name      = "External Remote Services"
+      reference = "https://attack.mitre.org/techniques/T1133/"
+    }
+  }
+
+  severity_mapping {
+    field    = "event.severity"
+    operator = "equals"
+    severity = "high"
+    value    = "90"
+  }
+
+  rule_content = jsonencode({
+    "rule_id" : "typed_hacker_rule",
+    "enabled" : true,
+    "interval" : "20m",
+    "from" : "now-10m",
+    # One alert per host every 30 minutes, threshold rules only support the duration
+    "alert_suppression" : {
+      "group_by" : ["host.name"],
+      "duration" : { "value" : 30, "unit" : "m" },
+      "missing_fields_strategy" : "suppress"
+    }
+  })
+}
+
+# ES|QL rules select their indices in the FROM command of the query and have no index.
+# Queries which do not aggregate their results request the _id metadata field, so that alerts are deduplicated.
+resource "elastic-siem-detection_detection_rule" "esql_rule" {
+  name        = "Hacker logins"
+  description = "Logins of the hacker user."
+  type        = "esql"
+  language    = "esql"
+  query       = "FROM logs-* METADATA _id, _index, _version | WHERE user.name == \"hacker\" AND event.action == \"login\""
+  severity    = "medium"
+  risk_score  = 47
+
+  rule_content = jsonencode({
+    "rule_id" : "esql_hacker_rule",
+    # Isolate the host of the alerts with Elastic Defend and collect its processes with Osquery
+    "response_actions" : [
+      {
+        "action_type_id" : ".endpoint",
+        "params" : { "command" : "isolate", "comment" : "Isolated after a hacker login" }
+      },
+      {
+        "action_type_id" : ".osquery",
+        "params" : { "query" : "SELECT * FROM processes" }
+      }
+    ]
+  })
+}
@@ -0,0 +1,5 @@
+# Import by identifier in the provider space
+terraform import elastic-siem-detection_exception_item.my_items 00000000-0000-0000-0000-000000000000
+
+# Import by identifier in a specific space
+terraform import elastic-siem-detection_exception_item.my_items security-ops/00000000-0000-0000-0000-000000000000
@@ -2,19 +2,31 @@ package helpers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
 // Client provides a connection to the Confluence API
 type Client struct {
-	client    *http.Client
-	baseURL   *url.URL
-	basePath  string
-	publicURL *url.URL
+	client     *http.Client
+	baseURL    *url.URL
+	basePath   string
+	pathPrefix string
+	publicURL  *url.URL
+	headers    map[string]string
+	apiKey     string
+	token      *tokenSource
+	spaceID    string
+	retry      RetryInput
+	trace      *traceWriter
+	throttle   *throttle
+	version    *Version
 }
 
 // NewClientInput provides information to connect to the Confluence API
@@ -24,43 +36,168 @@ type NewClientInput struct {
 	UseTls   bool
 	Username string
 	Password string
+	ApiKey   string
+	TLS      *TLSInput
+	SpaceID  string
+	Retry    *RetryInput
+	// Timeout is the time limit of a single request. DefaultRequestTimeout applies when it is zero,
+	// NoRequestTimeout disables it.
+	Timeout   time.Duration
+	TraceFile string
+	// Endpoint is the full URL of Kibana, including an optional path prefix. It takes precedence over Hostname, Port and UseTls.
+	Endpoint string
+	// ExtraHeaders are added to every request
+	ExtraHeaders map[string]string
+	// ProxyURL is the HTTP(S) proxy requests go through, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables are used when empty
+	ProxyURL string
+	Throttle ThrottleInput
+	// BearerToken is sent as "Authorization: Bearer <token>", e.g. an OAuth/OIDC access token or a service account token
+	BearerToken string
+	// TokenCommand prints a bearer token on its standard output. It is run when BearerToken is empty
+	// and whenever Kibana rejects the token.
+	TokenCommand []string
 }
 
-// ErrorResponse describes why a request failed
-type ErrorResponse struct {
-	StatusCode int    `json:"status_code,omitempty"`
-	Message    string `json:"message,omitempty"`
-}
+// DefaultRequestTimeout is the time limit of a single request when none is configured
+const DefaultRequestTimeout = 10 * time.Second
+
+// NoRequestTimeout disables the time limit of the requests
+const NoRequestTimeout time.Duration = -1
+
+// DefaultSpaceID is the identifier of the Kibana space used when none is configured
+const DefaultSpaceID = "default"
 
 // NewClient returns an authenticated client ready to use
-func NewClient(input *NewClientInput) *Client {
+func NewClient(input *NewClientInput) (*Client, error) {
+	tlsConfig, err := NewTLSConfig(input.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if input.ProxyURL != "" {
+		proxyURL, err := url.Parse(input.ProxyURL)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", input.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	var trace *traceWriter
+	if input.TraceFile != "" {
+		trace, err = newTraceWriter(input.TraceFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open trace file: %w", err)
+		}
+	}
+
+	retry := RetryInput{
+		MaxRetries: DefaultMaxRetries,
+		WaitMin:    DefaultRetryWaitMin,
+		WaitMax:    DefaultRetryWaitMax,
+	}
+	if input.Retry != nil {
+		retry = *input.Retry
+	}
+
 	publicURL := url.URL{
 		Scheme: ifThenElse(input.UseTls, "https", "http").(string),
 		Host:   fmt.Sprintf(`%s:%d`, input.Hostname, input.Port),
 	}
+	pathPrefix := ""
+	if input.Endpoint != "" {
+		endpoint, err := ParseEndpoint(input.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		publicURL = url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host}
+		pathPrefix = strings.TrimSuffix(endpoint.Path, "/")
+	}
 
-	// Default
-	basePath := "/api"
+	basePath := pathPrefix + spaceBasePath(input.SpaceID)
 
-	baseURL := url.URL{
-		Scheme: ifThenElse(input.UseTls, "https", "http").(string),
-		Host:   fmt.Sprintf(`%s:%d`, input.Hostname, input.Port),
+	baseURL := publicURL
+	var token *tokenSource
+	if input.BearerToken != "" || len(input.TokenCommand) > 0 {
+		token = newTokenSource(input.BearerToken, input.TokenCommand)
+	}
+	if input.ApiKey == "" && token == nil {
+		baseURL.User = url.UserPassword(input.Username, input.Password)
+	}
+	timeout := input.Timeout
+	if timeout == 0 {
+		timeout = DefaultRequestTimeout
+	} else if timeout < 0 {
+		timeout = 0
 	}
-	baseURL.User = url.UserPassword(input.Username, input.Password)
+
 	return &Client{
 		client: &http.Client{
-			Timeout: time.Second * 10,
+			Timeout:   timeout,
+			Transport: transport,
 		},
-		baseURL:   &baseURL,
-		basePath:  basePath,
-		publicURL: &publicURL,
+		baseURL:    &baseURL,
+		basePath:   basePath,
+		pathPrefix: pathPrefix,
+		publicURL:  &publicURL,
+		headers:    input.ExtraHeaders,
+		apiKey:     input.ApiKey,
+		token:      token,
+		spaceID:    input.SpaceID,
+		retry:      retry,
+		trace:      trace,
+		throttle:   newThrottle(input.Throttle),
+	}, nil
+}
+
+// ParseEndpoint parses the full URL of Kibana, which must be an absolute http or https URL without query
+func ParseEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint '%s': %w", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint '%s': expected an http or https URL such as https://kibana.example.com/kibana", endpoint)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid endpoint '%s': credentials, query and fragment are not supported", endpoint)
+	}
+	return u, nil
+}
+
+// spaceBasePath returns the API base path of a Kibana space
+func spaceBasePath(spaceID string) string {
+	if spaceID == "" || spaceID == DefaultSpaceID {
+		return "/api"
+	}
+	return "/s/" + url.PathEscape(spaceID) + "/api"
+}
+
+// WithSpace returns a copy of the client which sends its requests to the given Kibana space.
+// An empty space keeps the space of the client.
+func (c *Client) WithSpace(spaceID string) *Client {
+	if spaceID == "" {
+		return c
 	}
+	clone := *c
+	clone.spaceID = spaceID
+	clone.basePath = c.pathPrefix + spaceBasePath(spaceID)
+	return &clone
+}
+
+// SpaceID returns the Kibana space the client sends its requests to
+func (c *Client) SpaceID() string {
+	if c.spaceID == "" {
+		return DefaultSpaceID
+	}
+	return c.spaceID
 }
 
 // GetString uses the client to send a GET request and returns a string
-func (c *Client) GetString(path string) (string, error) {
+func (c *Client) GetString(ctx context.Context, path string) (string, error) {
 	body := new(bytes.Buffer)
-	responseBody, err := c.doRaw("GET", path, "", body)
+	responseBody, err := c.doRaw(ctx, "GET", path, "", body)
 	if err != nil {
 		return "", err
 	}
@@ -69,24 +206,24 @@ func (c *Client) GetString(path string) (string, error) {
 }
 
 // Get uses the client to send a GET request
-func (c *Client) Get(path string, result interface{}) error {
+func (c *Client) Get(ctx context.Context, path string, result interface{}) error {
 	body := new(bytes.Buffer)
-	return c.do("GET", path, "", body, result)
+	return c.do(ctx, "GET", path, "", body, result)
 }
 
-func (c *Client) GetRaw(path string) (*bytes.Buffer, error) {
+func (c *Client) GetRaw(ctx context.Context, path string) (*bytes.Buffer, error) {
 	body := new(bytes.Buffer)
-	return c.doRaw("GET", path, "", body)
+	return c.doRaw(ctx, "GET", path, "", body)
 }
 
 // Delete uses the client to send a DELETE request
-func (c *Client) Delete(path string) error {
+func (c *Client) Delete(ctx context.Context, path string) error {
 	body := new(bytes.Buffer)
-	return c.do("DELETE", path, "", body, nil)
+	return c.do(ctx, "DELETE", path, "", body, nil)
 }
 
 // Post uses the client to send a POST request
-func (c *Client) Post(path string, body interface{}, result interface{}, itemsToRemove []string) error {
+func (c *Client) Post(ctx context.Context, path string, body interface{}, result interface{}, itemsToRemove []string) error {
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return err
@@ -98,11 +235,11 @@ func (c *Client) Post(path string, body interface{}, result interface{}, itemsTo
 	if err != nil {
 		return err
 	}
-	return c.do("POST", path, "application/json", b, result)
+	return c.do(ctx, "POST", path, "application/json", b, result)
 }
 
 // Put uses the client to send a PUT request
-func (c *Client) Put(path string, body interface{}, result interface{}, itemsToRemove []string) error {
+func (c *Client) Put(ctx context.Context, path string, body interface{}, result interface{}, itemsToRemove []string) error {
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return err
@@ -114,7 +251,19 @@ func (c *Client) Put(path string, body interface{}, result interface{}, itemsToR
 	if err != nil {
 		return err
 	}
-	return c.do("PUT", path, "application/json", b, result)
+	return c.do(ctx, "PUT", path, "application/json", b, result)
+}
+
+// Patch uses the client to send a PATCH request
+func (c *Client) Patch(ctx context.Context, path string, body interface{}, result interface{}, itemsToRemove []string) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if len(itemsToRemove) > 0 {
+		RemoveKeysFromJSONObjectBytes(&bodyBytes, itemsToRemove)
+	}
+	return c.do(ctx, "PATCH", path, "application/json", bytes.NewBuffer(bodyBytes), result)
 }
 
 func JsonBytesBuffer(body interface{}) (*bytes.Buffer, error) {
@@ -137,88 +286,133 @@ func bytesBufferJSON(bodyBytes *bytes.Buffer, result interface{}) error {
 	return json.NewDecoder(reader).Decode(&result)
 }
 
-func (c *Client) do(method, path, contentType string, body *bytes.Buffer, result interface{}) error {
-	responseBody, err := c.doRaw(method, path, contentType, body)
+func (c *Client) do(ctx context.Context, method, path, contentType string, body *bytes.Buffer, result interface{}) error {
+	responseBody, err := c.doRaw(ctx, method, path, contentType, body)
 	if err != nil {
 		return err
 	}
 	return bytesBufferJSON(responseBody, result)
 }
 
-// do uses the client to send a specified request
-func (c *Client) doRaw(method, path, contentType string, body *bytes.Buffer) (*bytes.Buffer, error) {
-	fullPath := c.basePath + path
-	u, err := c.baseURL.Parse(fullPath)
+// newRequest builds a request to the API with the headers every call needs
+func (c *Client) newRequest(ctx context.Context, method, u, contentType string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(method, u.String(), body)
-	if err != nil {
-		return nil, err
+	for name, value := range c.headers {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
 	}
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
 	req.Header.Add("kbn-xsrf", "monitoring")
-	// LOGGING LOCALLY FOR DEBUGGIN PURPOSES. Uncomment to view raw requests.
-	// f, err := os.OpenFile("/tmp/httputildebug.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0755)
-	// f.WriteString("[doRaw]\n")
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// defer f.Close()
-	// dump, err := httputil.DumpRequestOut(req, true)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// _, err2 := f.Write(dump)
-	// if err2 != nil {
-	// 	log.Fatal(err2)
-	// }
-	// f.WriteString("\n")
-	//
-	resp, err := c.client.Do(req)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	} else if c.token != nil {
+		req.Header.Set("Authorization", "Bearer "+c.token.current())
+	}
+	return req, nil
+}
+
+// doRaw uses the client to send a specified request, retrying it on transient failures
+func (c *Client) doRaw(ctx context.Context, method, path, contentType string, body *bytes.Buffer) (*bytes.Buffer, error) {
+	fullPath := c.basePath + path
+	u, err := c.baseURL.Parse(fullPath)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	// Keep the body around to send it again on retries
+	bodyBytes := body.Bytes()
+	if c.token != nil {
+		if _, err := c.token.get(ctx); err != nil {
+			return nil, err
+		}
+	}
+	logCtx := c.logContext(ctx)
+	refreshed := false
+
+	var resp *http.Response
+	var responseBody []byte
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, u.String(), contentType, bodyBytes)
+		if err != nil {
+			return nil, err
+		}
+		release, err := c.throttle.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.traceRequest(logCtx, req, bodyBytes, attempt)
+		start := time.Now()
+		resp, err = c.client.Do(req)
+		if err == nil {
+			responseBody, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		release()
+		c.traceResponse(logCtx, req, resp, responseBody, err, attempt, time.Since(start))
+		if err != nil {
+			// Stop right away when Terraform cancels the operation
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if attempt < c.retry.MaxRetries && shouldRetryError(method, err) {
+				if err := sleepContext(ctx, c.retry.backoff(attempt, nil)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		// Refresh a rejected bearer token once, e.g. when it expired during the apply
+		if resp.StatusCode == http.StatusUnauthorized && c.token != nil && !refreshed {
+			refreshed = true
+			rejected := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+			retry, err := c.token.refresh(ctx, rejected)
+			if err != nil {
+				return nil, err
+			}
+			if retry {
+				logCtx = c.logContext(ctx)
+				continue
+			}
+		}
+		if attempt < c.retry.MaxRetries && shouldRetryStatus(method, resp.StatusCode) {
+			if err := sleepContext(ctx, c.retry.backoff(attempt, resp)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		break
+	}
 	var expectedStatusCode = map[string][]int{
 		"POST":   {200, 201},
 		"PUT":    {200},
+		"PATCH":  {200},
 		"GET":    {200},
 		"DELETE": {200, 204},
 	}
 	if !contains(expectedStatusCode[method], resp.StatusCode) {
-		var responseBody string
-		var errResponse ErrorResponse
-		err = json.NewDecoder(resp.Body).Decode(&errResponse)
-		if err != nil {
-			responseBody = "Could not decode error"
-		} else {
-			responseBody = errResponse.String()
-		}
-		s := body.String()
-		return nil, fmt.Errorf("%s\n\n%s %s\n%s\n\n%s",
-			resp.Status, method, fullPath, s, responseBody)
+		return nil, newAPIError(resp, responseBody, method, fullPath, redactBody(bodyBytes))
 	}
-	result := new(bytes.Buffer)
-	_, err = result.ReadFrom(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	return result, nil
-}
-
-func (e *ErrorResponse) String() string {
-	return fmt.Sprintf("%s\nCode: %d",
-		e.Message, e.StatusCode)
+	return bytes.NewBuffer(responseBody), nil
 }
 
 // URL returns the public URL for a given path
 func (c *Client) URL(path string) string {
-	u, err := c.publicURL.Parse(path)
+	u, err := c.publicURL.Parse(c.pathPrefix + path)
 	if err != nil {
 		return ""
 	}
 	return u.String()
 }
+
+// SpaceURL returns the public URL of a Kibana page in the space of the client
+func (c *Client) SpaceURL(path string) string {
+	return c.URL(strings.TrimSuffix(spaceBasePath(c.spaceID), "/api") + path)
+}
@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultCloudPort is the port of Elastic Cloud deployments when the Cloud ID does not include one
+const DefaultCloudPort = 443
+
+// ParseCloudID returns the Kibana host and port of an Elastic Cloud ID.
+// A Cloud ID is "<name>:<base64(<domain>[:<port>]$<elasticsearch id>$<kibana id>)>",
+// and the Kibana host is "<kibana id>.<domain>".
+func ParseCloudID(cloudID string) (string, int, error) {
+	// The name may contain colons, the base64 data cannot
+	separator := strings.LastIndex(cloudID, ":")
+	encoded := strings.TrimSpace(cloudID[separator+1:])
+	if separator < 0 || encoded == "" {
+		return "", 0, fmt.Errorf("invalid cloud ID: expected <name>:<base64 encoded data>")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Some tools strip the padding
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid cloud ID: unable to decode the base64 data: %w", err)
+		}
+	}
+	parts := strings.Split(string(decoded), "$")
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return "", 0, fmt.Errorf("invalid cloud ID: expected <domain>$<elasticsearch id>$<kibana id> in the decoded data")
+	}
+	if parts[2] == "" {
+		return "", 0, fmt.Errorf("invalid cloud ID: the deployment has no Kibana instance")
+	}
+
+	domain, port := parts[0], DefaultCloudPort
+	if host, portString, err := net.SplitHostPort(domain); err == nil {
+		port, err = strconv.Atoi(portString)
+		if err != nil || port <= 0 || port > 65535 {
+			return "", 0, fmt.Errorf("invalid cloud ID: invalid port '%s'", portString)
+		}
+		domain = host
+	}
+	// The Kibana id may also carry the port
+	kibanaID := parts[2]
+	if host, portString, err := net.SplitHostPort(kibanaID); err == nil {
+		port, err = strconv.Atoi(portString)
+		if err != nil || port <= 0 || port > 65535 {
+			return "", 0, fmt.Errorf("invalid cloud ID: invalid port '%s'", portString)
+		}
+		kibanaID = host
+	}
+	return kibanaID + "." + domain, port, nil
+}
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseCloudID(t *testing.T) {
+	tests := map[string]struct {
+		host string
+		port int
+	}{
+		// Sample from the Elastic Cloud documentation
+		"staging:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbyRjZWM2ZjI2MWE3NGJmMjRjZTMzYmI4ODExYjg0Mjk0ZiRjNmMyY2E2ZDA0MjI0OWFmMGNjN2Q3YTllOTYyNTc0Mw==": {
+			host: "c6c2ca6d042249af0cc7d7a9e9625743.us-east-1.aws.found.io",
+			port: 443,
+		},
+		// Without padding
+		"staging:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbyRjZWM2ZjI2MWE3NGJmMjRjZTMzYmI4ODExYjg0Mjk0ZiRjNmMyY2E2ZDA0MjI0OWFmMGNjN2Q3YTllOTYyNTc0Mw": {
+			host: "c6c2ca6d042249af0cc7d7a9e9625743.us-east-1.aws.found.io",
+			port: 443,
+		},
+		"custom-port:" + base64.StdEncoding.EncodeToString([]byte("europe-west1.gcp.cloud.es.io:9243$es-id$kibana-id")): {
+			host: "kibana-id.europe-west1.gcp.cloud.es.io",
+			port: 9243,
+		},
+		"kibana-port:" + base64.StdEncoding.EncodeToString([]byte("eastus2.azure.elastic-cloud.com$es-id:9243$kibana-id:9244")): {
+			host: "kibana-id.eastus2.azure.elastic-cloud.com",
+			port: 9244,
+		},
+		// The name may contain colons
+		"my:deployment:" + base64.StdEncoding.EncodeToString([]byte("us-east-1.aws.found.io$es-id$kibana-id")): {
+			host: "kibana-id.us-east-1.aws.found.io",
+			port: 443,
+		},
+	}
+	for cloudID, expected := range tests {
+		host, port, err := ParseCloudID(cloudID)
+		if err != nil {
+			t.Errorf("Unexpected error for '%s': %s", cloudID, err)
+			continue
+		}
+		if host != expected.host || port != expected.port {
+			t.Errorf("Cloud ID '%s': expected %s:%d, got %s:%d", cloudID, expected.host, expected.port, host, port)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"staging",
+		"staging:",
+		"staging:not base64!",
+		"staging:" + base64.StdEncoding.EncodeToString([]byte("us-east-1.aws.found.io$es-id")),
+		"staging:" + base64.StdEncoding.EncodeToString([]byte("us-east-1.aws.found.io$es-id$")),
+		"staging:" + base64.StdEncoding.EncodeToString([]byte("us-east-1.aws.found.io:http$es-id$kibana-id")),
+	}
+	for _, cloudID := range invalid {
+		if _, _, err := ParseCloudID(cloudID); err == nil {
+			t.Errorf("Expected cloud ID '%s' to be rejected", cloudID)
+		}
+	}
+}
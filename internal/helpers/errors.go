@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorResponse describes why a request failed, as decoded from the Kibana error body.
+// Kibana core routes use "statusCode" while the security solution routes use "status_code".
+type ErrorResponse struct {
+	StatusCode      int                    `json:"statusCode,omitempty"`
+	SnakeStatusCode int                    `json:"status_code,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+	Message         string                 `json:"message,omitempty"`
+	Attributes      map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func (e *ErrorResponse) String() string {
+	s := fmt.Sprintf("%s\nCode: %d", e.Message, e.code())
+	if e.Error != "" {
+		s = fmt.Sprintf("%s: %s", e.Error, s)
+	}
+	if len(e.Attributes) > 0 {
+		if attributes, err := json.Marshal(e.Attributes); err == nil {
+			s = fmt.Sprintf("%s\nAttributes: %s", s, attributes)
+		}
+	}
+	return s
+}
+
+func (e *ErrorResponse) code() int {
+	if e.StatusCode != 0 {
+		return e.StatusCode
+	}
+	return e.SnakeStatusCode
+}
+
+// APIError is returned when Kibana answers a request with an unexpected status code
+type APIError struct {
+	StatusCode  int
+	Status      string
+	Method      string
+	Path        string
+	RequestBody string
+	// Response is nil when the error body could not be decoded
+	Response *ErrorResponse
+}
+
+func (e *APIError) Error() string {
+	responseBody := "Could not decode error"
+	if e.Response != nil {
+		responseBody = e.Response.String()
+	}
+	return fmt.Sprintf("%s\n\n%s %s\n%s\n\n%s",
+		e.Status, e.Method, e.Path, e.RequestBody, responseBody)
+}
+
+// Message returns the error message sent by Kibana, or the HTTP status when there is none
+func (e *APIError) Message() string {
+	if e.Response != nil && e.Response.Message != "" {
+		return e.Response.Message
+	}
+	return e.Status
+}
+
+// newAPIError builds the error of a response with an unexpected status code
+func newAPIError(resp *http.Response, responseBody []byte, method, path, requestBody string) *APIError {
+	apiErr := &APIError{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		Method:      method,
+		Path:        path,
+		RequestBody: requestBody,
+	}
+	var errResponse ErrorResponse
+	if err := json.Unmarshal(responseBody, &errResponse); err == nil {
+		apiErr.Response = &errResponse
+	}
+	return apiErr
+}
+
+// StatusCode returns the HTTP status code of an API error, or 0 for any other error
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether the error is an API error with a 404 status code
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsConflict reports whether the error is an API error with a 409 status code
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+// IsForbidden reports whether the error is an API error with a 403 status code
+func IsForbidden(err error) bool {
+	return StatusCode(err) == http.StatusForbidden
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientAPIError(t *testing.T) {
+	var status int
+	var body string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key"})
+
+	status = http.StatusNotFound
+	body = `{"statusCode":404,"error":"Not Found","message":"rule_id: \"my_rule\" not found","attributes":{"rule_id":"my_rule"}}`
+	err := client.Get(context.Background(), "/detection_engine/rules?rule_id=my_rule", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got: %v", err)
+	}
+	if apiErr.StatusCode != 404 || apiErr.Method != "GET" || apiErr.Path != "/api/detection_engine/rules?rule_id=my_rule" {
+		t.Errorf("Unexpected APIError: %+v", apiErr)
+	}
+	if apiErr.Response == nil || apiErr.Response.Error != "Not Found" || apiErr.Response.Attributes["rule_id"] != "my_rule" {
+		t.Errorf("Expected the Kibana error body to be decoded, got: %+v", apiErr.Response)
+	}
+	if apiErr.Message() != `rule_id: "my_rule" not found` {
+		t.Errorf("Unexpected message: %s", apiErr.Message())
+	}
+	if !IsNotFound(err) || IsConflict(err) || IsForbidden(err) {
+		t.Errorf("Expected the error to only be a not found error")
+	}
+
+	// Security solution routes use snake case
+	status = http.StatusConflict
+	body = `{"message":"rule_id: \"my_rule\" already exists","status_code":409}`
+	err = client.Post(context.Background(), "/detection_engine/rules", map[string]string{"rule_id": "my_rule"}, nil, nil)
+	if !IsConflict(err) || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected a conflict error, got: %v", err)
+	}
+
+	// A 404 inside the error message is not a not found error
+	status = http.StatusBadRequest
+	body = `{"statusCode":400,"error":"Bad Request","message":"risk_score 404 is out of range"}`
+	err = client.Get(context.Background(), "/detection_engine/rules", nil)
+	if IsNotFound(err) || StatusCode(err) != 400 {
+		t.Errorf("Expected a bad request error, got: %v", err)
+	}
+
+	status = http.StatusForbidden
+	body = `not json`
+	err = client.Delete(context.Background(), "/exception_lists?id=1")
+	if !IsForbidden(err) || !errors.As(err, &apiErr) || apiErr.Response != nil {
+		t.Errorf("Expected a forbidden error without a decoded body, got: %v", err)
+	}
+}
@@ -71,19 +71,6 @@ func Convert(i interface{}) interface{} {
 }
 
 func ObjectFromJSON(jsonString string, result interface{}) error {
-	// LOGGING LOCALLY FOR DEBUGGIN PURPOSES. Uncomment to object conversions.
-	// f, err := os.OpenFile("/tmp/httputildebug.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0755)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// defer f.Close()
-	// f.WriteString("[ObjectFromJSON]\n")
-	// _, err2 := f.WriteString(jsonString)
-	// if err2 != nil {
-	// 	log.Fatal(err2)
-	// }
-	// f.WriteString("\n")
-	// END OF LOGGING
 	return json.Unmarshal([]byte(jsonString), &result)
 }
 
@@ -93,19 +80,6 @@ func JSONfromObject(obj interface{}, keys []string) (string, error) {
 		return "", err
 	}
 	RemoveKeysFromJSONObjectBytes(&jsonBytes, keys)
-	// LOGGING LOCALLY FOR DEBUGGIN PURPOSES. Uncomment to object conversions.
-	// f, err := os.OpenFile("/tmp/httputildebug.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0755)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// defer f.Close()
-	// f.WriteString("[JSONfromObject]\n")
-	// _, err2 := f.WriteString(string(jsonBytes))
-	// if err2 != nil {
-	// 	log.Fatal(err2)
-	// }
-	// f.WriteString("\n")
-	// END OF LOGGING
 	return string(jsonBytes), nil
 }
 
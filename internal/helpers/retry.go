@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryInput configures how failed requests are retried
+type RetryInput struct {
+	MaxRetries int
+	WaitMin    time.Duration
+	WaitMax    time.Duration
+}
+
+// Defaults of the retry configuration
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryWaitMin = 1 * time.Second
+	DefaultRetryWaitMax = 30 * time.Second
+)
+
+// isPreRequestError reports whether the request failed before reaching Kibana,
+// in which case it is safe to retry any method.
+func isPreRequestError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// shouldRetryError reports whether a request which failed with a transport error can be retried
+func shouldRetryError(method string, err error) bool {
+	// Certificate problems will not go away by retrying
+	var certErr *tls.CertificateVerificationError
+	var alertErr tls.AlertError
+	if errors.As(err, &certErr) || errors.As(err, &alertErr) {
+		return false
+	}
+	if isPreRequestError(err) {
+		return true
+	}
+	// The request may have been processed, only retry idempotent methods
+	return method != http.MethodPost
+}
+
+// shouldRetryStatus reports whether a request which received the given status code can be retried
+func shouldRetryStatus(method string, statusCode int) bool {
+	// Rate limited requests are rejected before being processed
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if method == http.MethodPost {
+		return false
+	}
+	return statusCode >= 500 && statusCode != http.StatusNotImplemented
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// backoff returns how long to wait before the given retry attempt (starting at 0).
+// The Retry-After header of the response takes precedence over the exponential backoff,
+// both being capped by the maximum wait.
+func (r RetryInput) backoff(attempt int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		if r.WaitMax > 0 && wait > r.WaitMax {
+			return r.WaitMax
+		}
+		return wait
+	}
+
+	wait := r.WaitMin << attempt
+	if wait <= 0 || (r.WaitMax > 0 && wait > r.WaitMax) {
+		wait = r.WaitMax
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	// Jitter between half and the full wait to spread parallel requests
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleepContext waits for the given duration, returning early when the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
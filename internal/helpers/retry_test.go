@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientRetries(t *testing.T) {
+	var requests int
+	var statusCodes []int
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statusCodes[requests]
+		requests++
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	retry := &RetryInput{MaxRetries: 2, WaitMin: time.Millisecond, WaitMax: 5 * time.Millisecond}
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key", Retry: retry})
+
+	tests := []struct {
+		name             string
+		method           string
+		statusCodes      []int
+		expectedRequests int
+		expectError      bool
+	}{
+		{"GET succeeds after server errors", http.MethodGet, []int{503, 502, 200}, 3, false},
+		{"GET gives up after max retries", http.MethodGet, []int{500, 500, 500}, 3, true},
+		{"GET is not retried on client errors", http.MethodGet, []int{404}, 1, true},
+		{"POST is retried when rate limited", http.MethodPost, []int{429, 200}, 2, false},
+		{"POST is not retried on server errors", http.MethodPost, []int{503}, 1, true},
+		{"PUT is retried on server errors", http.MethodPut, []int{504, 200}, 2, false},
+	}
+	for _, test := range tests {
+		requests = 0
+		statusCodes = test.statusCodes
+		var err error
+		switch test.method {
+		case http.MethodGet:
+			_, err = client.GetString(context.Background(), "/detection_engine/rules")
+		case http.MethodPost:
+			err = client.Post(context.Background(), "/detection_engine/rules", map[string]string{"name": "rule"}, nil, nil)
+		case http.MethodPut:
+			err = client.Put(context.Background(), "/detection_engine/rules", map[string]string{"name": "rule"}, nil, nil)
+		}
+		if (err != nil) != test.expectError {
+			t.Errorf("%s: unexpected error result: %v", test.name, err)
+		}
+		if requests != test.expectedRequests {
+			t.Errorf("%s: expected %d requests, got %d", test.name, test.expectedRequests, requests)
+		}
+	}
+}
+
+func TestClientRetriesConnectionErrors(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	retry := &RetryInput{MaxRetries: 1, WaitMin: time.Millisecond, WaitMax: time.Millisecond}
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key", Retry: retry})
+	svr.Close()
+
+	// Connection refused happens before the request is sent, so POST is retried as well
+	if err := client.Post(context.Background(), "/detection_engine/rules", map[string]string{}, nil, nil); err == nil || !isPreRequestError(err) {
+		t.Errorf("Expected a pre-request connection error, got: %v", err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retry := RetryInput{MaxRetries: 5, WaitMin: 100 * time.Millisecond, WaitMax: time.Second}
+
+	for attempt := 0; attempt < 6; attempt++ {
+		wait := retry.backoff(attempt, nil)
+		expected := retry.WaitMin << attempt
+		if expected > retry.WaitMax {
+			expected = retry.WaitMax
+		}
+		if wait < expected/2 || wait > expected {
+			t.Errorf("Attempt %d: expected a wait between %s and %s, got %s", attempt, expected/2, expected, wait)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "0")
+	if wait := retry.backoff(3, resp); wait != 0 {
+		t.Errorf("Expected Retry-After to take precedence, got %s", wait)
+	}
+	resp.Header.Set("Retry-After", "120")
+	if wait := retry.backoff(0, resp); wait != retry.WaitMax {
+		t.Errorf("Expected Retry-After to be capped to %s, got %s", retry.WaitMax, wait)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	if wait := retry.backoff(0, resp); wait != 0 {
+		t.Errorf("Expected a Retry-After date in the past not to wait, got %s", wait)
+	}
+}
@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// ThrottleInput limits the load put on Kibana. Zero values disable the limits.
+type ThrottleInput struct {
+	// MaxConcurrentRequests is the number of requests in flight at the same time
+	MaxConcurrentRequests int
+	// RequestsPerSecond is the sustained rate of requests, bursts up to one second of requests are allowed
+	RequestsPerSecond float64
+}
+
+// throttle is shared by all the copies of a client, so the limits apply to the whole provider
+type throttle struct {
+	// semaphore holds a token per request in flight, nil when the concurrency is not limited
+	semaphore chan struct{}
+	// bucket is nil when the rate is not limited
+	bucket *tokenBucket
+}
+
+func newThrottle(input ThrottleInput) *throttle {
+	t := &throttle{}
+	if input.MaxConcurrentRequests > 0 {
+		t.semaphore = make(chan struct{}, input.MaxConcurrentRequests)
+	}
+	if input.RequestsPerSecond > 0 {
+		t.bucket = newTokenBucket(input.RequestsPerSecond)
+	}
+	return t
+}
+
+// acquire waits until a request can be sent, the returned function must be called once it is done
+func (t *throttle) acquire(ctx context.Context) (func(), error) {
+	if t == nil {
+		return func() {}, nil
+	}
+	// Wait for the rate limit first, so that waiting requests do not hold a concurrency slot
+	if t.bucket != nil {
+		if err := t.bucket.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if t.semaphore == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.semaphore <- struct{}{}:
+		return func() { <-t.semaphore }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tokenBucket is refilled at rate tokens per second, up to burst tokens
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := math.Max(1, math.Ceil(rate))
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// take consumes a token when one is available, otherwise it returns how long to wait for the next one
+func (b *tokenBucket) take() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait blocks until a token is consumed or the context is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		d := b.take()
+		if d == 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+}
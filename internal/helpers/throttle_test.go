@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientMaxConcurrentRequests(t *testing.T) {
+	var inFlight, maxInFlight int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			previous := atomic.LoadInt32(&maxInFlight)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxInFlight, previous, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key", Throttle: ThrottleInput{MaxConcurrentRequests: 2}})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		// Resources of different spaces share the limit
+		spaceClient := client.WithSpace([]string{"default", "team"}[i%2])
+		go func() {
+			defer wg.Done()
+			if _, err := spaceClient.GetString(context.Background(), "/status"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+
+	// Waiting for a slot stops with the context
+	client = newTestClient(t, svr, NewClientInput{ApiKey: "key", Throttle: ThrottleInput{MaxConcurrentRequests: 1}})
+	client.throttle.semaphore <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetString(ctx, "/status"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context deadline to stop the wait, got: %v", err)
+	}
+}
+
+func TestClientRequestsPerSecond(t *testing.T) {
+	var count int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	// A burst of 50 requests, then 10 requests at 50 per second
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key", Throttle: ThrottleInput{RequestsPerSecond: 50}})
+	start := time.Now()
+	for i := 0; i < 60; i++ {
+		if _, err := client.GetString(context.Background(), "/status"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected the requests to be rate limited, took %s", elapsed)
+	}
+	if count != 60 {
+		t.Errorf("Expected 60 requests, got %d", count)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(0.5)
+	if bucket.burst != 1 {
+		t.Errorf("Expected a burst of at least one request, got %f", bucket.burst)
+	}
+	if d := bucket.take(); d != 0 {
+		t.Errorf("Expected the first token to be available, got a wait of %s", d)
+	}
+	if d := bucket.take(); d < 1900*time.Millisecond || d > 2*time.Second {
+		t.Errorf("Expected a wait of about 2s for the next token, got %s", d)
+	}
+}
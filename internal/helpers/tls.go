@@ -0,0 +1,81 @@
+package helpers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSInput provides the TLS settings used to connect to Kibana
+type TLSInput struct {
+	CAFile             string
+	CAPem              string
+	ClientCert         string
+	ClientKey          string
+	InsecureSkipVerify bool
+}
+
+// pemOrFile returns the PEM content as is, or reads it from the given file path
+func pemOrFile(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+// NewTLSConfig builds the TLS configuration of the client. A nil configuration is
+// returned when nothing differs from the defaults of the Go HTTP transport.
+func NewTLSConfig(input *TLSInput) (*tls.Config, error) {
+	if input == nil || (input.CAFile == "" && input.CAPem == "" && input.ClientCert == "" && input.ClientKey == "" && !input.InsecureSkipVerify) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: input.InsecureSkipVerify,
+	}
+
+	if input.CAFile != "" || input.CAPem != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if input.CAFile != "" {
+			caBytes, err := os.ReadFile(input.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(caBytes) {
+				return nil, fmt.Errorf("no PEM encoded certificates found in CA file %s", input.CAFile)
+			}
+		}
+		if input.CAPem != "" {
+			if !pool.AppendCertsFromPEM([]byte(input.CAPem)) {
+				return nil, fmt.Errorf("no PEM encoded certificates found in CA PEM")
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if input.ClientCert != "" || input.ClientKey != "" {
+		if input.ClientCert == "" || input.ClientKey == "" {
+			return nil, fmt.Errorf("both a client certificate and a client key are required")
+		}
+		certBytes, err := pemOrFile(input.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client certificate: %w", err)
+		}
+		keyBytes, err := pemOrFile(input.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certBytes, keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
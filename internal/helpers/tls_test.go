@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generateClientCertificate returns a self-signed client certificate and its key, PEM encoded
+func generateClientCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "terraform"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return string(certPem), string(keyPem)
+}
+
+func serverCAPem(svr *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: svr.Certificate().Raw}))
+}
+
+func TestClientTLS(t *testing.T) {
+	svr := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key"})
+	if _, err := client.GetString(context.Background(), "/status"); err == nil {
+		t.Errorf("Expected a self-signed certificate to be rejected without a CA")
+	}
+
+	client = newTestClient(t, svr, NewClientInput{ApiKey: "key", TLS: &TLSInput{CAPem: serverCAPem(svr)}})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Errorf("Expected the certificate to be trusted with ca_pem, got: %s", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(serverCAPem(svr)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	client = newTestClient(t, svr, NewClientInput{ApiKey: "key", TLS: &TLSInput{CAFile: caFile}})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Errorf("Expected the certificate to be trusted with ca_file, got: %s", err)
+	}
+
+	client = newTestClient(t, svr, NewClientInput{ApiKey: "key", TLS: &TLSInput{InsecureSkipVerify: true}})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Errorf("Expected the certificate to be accepted with insecure_skip_verify, got: %s", err)
+	}
+}
+
+func TestClientTLSClientCertificate(t *testing.T) {
+	certPem, keyPem := generateClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM([]byte(certPem))
+
+	svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	svr.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	svr.StartTLS()
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key", TLS: &TLSInput{CAPem: serverCAPem(svr)}})
+	if _, err := client.GetString(context.Background(), "/status"); err == nil {
+		t.Errorf("Expected the request to fail without a client certificate")
+	}
+
+	client = newTestClient(t, svr, NewClientInput{ApiKey: "key", TLS: &TLSInput{
+		CAPem:      serverCAPem(svr),
+		ClientCert: certPem,
+		ClientKey:  keyPem,
+	}})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Errorf("Expected the client certificate to be accepted, got: %s", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, []byte(certPem), 0600)
+	os.WriteFile(keyFile, []byte(keyPem), 0600)
+	client = newTestClient(t, svr, NewClientInput{ApiKey: "key", TLS: &TLSInput{
+		CAPem:      serverCAPem(svr),
+		ClientCert: certFile,
+		ClientKey:  keyFile,
+	}})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Errorf("Expected the client certificate files to be accepted, got: %s", err)
+	}
+
+	if _, err := NewTLSConfig(&TLSInput{ClientCert: certPem}); err == nil {
+		t.Errorf("Expected an error when the client key is missing")
+	}
+}
@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// tokenSource provides the bearer token of a client, such as an OAuth/OIDC access token or an
+// Elasticsearch service account token. It is shared by all the copies of a client.
+type tokenSource struct {
+	mu    sync.Mutex
+	token string
+	// command prints a new token on its standard output, it is empty when the token cannot be refreshed
+	command []string
+}
+
+func newTokenSource(token string, command []string) *tokenSource {
+	return &tokenSource{token: token, command: command}
+}
+
+// get returns the current token, running the token command when there is none yet
+func (s *tokenSource) get(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == "" {
+		if err := s.run(ctx); err != nil {
+			return "", err
+		}
+	}
+	return s.token, nil
+}
+
+// refresh obtains a new token after Kibana rejected the given one. It reports whether the request can be sent again,
+// which is also the case when a concurrent request already refreshed the token.
+func (s *tokenSource) refresh(ctx context.Context, rejected string) (bool, error) {
+	if len(s.command) == 0 {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != rejected {
+		return true, nil
+	}
+	if err := s.run(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// current returns the token without obtaining one. newRequest sends it in the Authorization header, so get must have
+// obtained it before the request is built, and the trace masks it in logs.
+func (s *tokenSource) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// run executes the token command and stores the token it printed. The caller must hold the lock.
+func (s *tokenSource) run(ctx context.Context) error {
+	if len(s.command) == 0 {
+		return errors.New("no bearer token is configured")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// The standard output may hold a token, only the error output is reported
+		return fmt.Errorf("token command '%s' failed: %w: %s", s.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return fmt.Errorf("token command '%s' did not print a token", s.command[0])
+	}
+	s.token = token
+	return nil
+}
@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// counterCommand returns a command printing token-1, token-2, ... on every run
+func counterCommand(t *testing.T) ([]string, string) {
+	t.Helper()
+	counter := filepath.Join(t.TempDir(), "counter")
+	script := `n=$(cat "$1" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$1"; echo "token-$n"`
+	return []string{"sh", "-c", script, "sh", counter}, counter
+}
+
+func TestClientBearerToken(t *testing.T) {
+	var authorization string
+	validToken := "static-token"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if authorization != "Bearer "+validToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"statusCode":401,"error":"Unauthorized","message":"token expired"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{BearerToken: "static-token"})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Bearer static-token" {
+		t.Errorf("Expected the bearer token to be sent, got '%s'", authorization)
+	}
+
+	// A static token cannot be refreshed
+	validToken = "other-token"
+	if _, err := client.GetString(context.Background(), "/status"); StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized error, got: %v", err)
+	}
+}
+
+func TestClientTokenCommand(t *testing.T) {
+	command, counter := counterCommand(t)
+	var requests int
+	validToken := "token-1"
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer "+validToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{TokenCommand: command})
+	if _, err := client.GetString(context.Background(), "/status"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The token expires: it is refreshed once and the request is sent again
+	validToken = "token-2"
+	requests = 0
+	if _, err := client.WithSpace("team").GetString(context.Background(), "/status"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected the request to be sent again after the refresh, got %d requests", requests)
+	}
+
+	// A refreshed token which is still rejected is not refreshed again
+	validToken = "never"
+	requests = 0
+	if _, err := client.GetString(context.Background(), "/status"); StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized error, got: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected a single refresh, got %d requests", requests)
+	}
+	if count, _ := os.ReadFile(counter); strings.TrimSpace(string(count)) != "3" {
+		t.Errorf("Expected the token command to run 3 times, got %s", count)
+	}
+}
+
+func TestTokenCommandErrors(t *testing.T) {
+	source := newTokenSource("", []string{"sh", "-c", "echo leaked-token; echo 'not logged in' >&2; exit 1"})
+	_, err := source.get(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not logged in") || strings.Contains(err.Error(), "leaked-token") {
+		t.Errorf("Expected the error output without the token, got: %v", err)
+	}
+
+	source = newTokenSource("", []string{"true"})
+	if _, err := source.get(context.Background()); err == nil {
+		t.Errorf("Expected an error when the command prints no token")
+	}
+}
@@ -0,0 +1,211 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// logSubsystem is the tflog subsystem of the HTTP traffic
+const logSubsystem = "kibana_http"
+
+// logLevelEnv sets the log level of the HTTP traffic independently of TF_LOG
+const logLevelEnv = "TF_LOG_PROVIDER_KIBANA_HTTP"
+
+// redacted replaces every secret in logs and traces
+const redacted = "***"
+
+// sensitiveKeyRegex matches the JSON keys and headers whose values are secrets,
+// such as the credentials found in action params
+var sensitiveKeyRegex = regexp.MustCompile(`(?i)(authorization|cookie|password|passwd|secret|token|api_?key|credential|private_?key)`)
+
+// traceWriter appends one JSON object per request and response to a trace file
+type traceWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// traceEntry is a line of the trace file
+type traceEntry struct {
+	Time       time.Time         `json:"time"`
+	Type       string            `json:"type"`
+	Attempt    int               `json:"attempt"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code,omitempty"`
+	DurationMs int64             `json:"duration_ms,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// newTraceWriter opens the trace file in append mode, readable by the current user only
+func newTraceWriter(path string) (*traceWriter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &traceWriter{file: file}, nil
+}
+
+func (w *traceWriter) write(entry *traceEntry) {
+	if w == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.file.Write(append(line, '\n'))
+}
+
+// redactHeaders returns the headers with the values of sensitive headers masked
+func redactHeaders(headers http.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if len(values) == 0 {
+			continue
+		}
+		if sensitiveKeyRegex.MatchString(name) {
+			result[name] = redacted
+		} else {
+			result[name] = values[0]
+		}
+	}
+	return result
+}
+
+// redactValue masks the values of sensitive keys in a decoded JSON value
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if sensitiveKeyRegex.MatchString(key) {
+				if _, isObject := item.(map[string]interface{}); !isObject {
+					v[key] = redacted
+					continue
+				}
+			}
+			v[key] = redactValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+// redactBody masks the values of sensitive keys in a JSON body. Bodies which are not JSON are returned as is.
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	redactedBody, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return string(body)
+	}
+	return string(redactedBody)
+}
+
+// logContext returns the context used to log the HTTP traffic, masking the secrets of the client
+func (c *Client) logContext(ctx context.Context) context.Context {
+	if os.Getenv(logLevelEnv) != "" {
+		ctx = tflog.NewSubsystem(ctx, logSubsystem, tflog.WithLevelFromEnv(logLevelEnv))
+	} else {
+		ctx = tflog.NewSubsystem(ctx, logSubsystem)
+	}
+	ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, logSubsystem, "Authorization")
+	secrets := []string{}
+	if password, ok := c.baseURL.User.Password(); ok && password != "" {
+		secrets = append(secrets, password)
+	}
+	if c.apiKey != "" {
+		secrets = append(secrets, c.apiKey)
+	}
+	if c.token != nil && c.token.current() != "" {
+		secrets = append(secrets, c.token.current())
+	}
+	if len(secrets) > 0 {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, logSubsystem, secrets...)
+		ctx = tflog.SubsystemMaskMessageStrings(ctx, logSubsystem, secrets...)
+	}
+	return ctx
+}
+
+// traceRequest logs a request about to be sent
+func (c *Client) traceRequest(ctx context.Context, req *http.Request, body []byte, attempt int) {
+	headers := redactHeaders(req.Header)
+	redactedBody := redactBody(body)
+	tflog.SubsystemDebug(ctx, logSubsystem, "Sending request to Kibana", map[string]interface{}{
+		"method":  req.Method,
+		"url":     req.URL.Redacted(),
+		"attempt": attempt,
+	})
+	tflog.SubsystemTrace(ctx, logSubsystem, "Request details", map[string]interface{}{
+		"method":  req.Method,
+		"url":     req.URL.Redacted(),
+		"headers": headers,
+		"body":    redactedBody,
+	})
+	c.trace.write(&traceEntry{
+		Time:    time.Now(),
+		Type:    "request",
+		Attempt: attempt,
+		Method:  req.Method,
+		URL:     req.URL.Redacted(),
+		Headers: headers,
+		Body:    redactedBody,
+	})
+}
+
+// traceResponse logs the response of a request, or the error which prevented receiving it
+func (c *Client) traceResponse(ctx context.Context, req *http.Request, resp *http.Response, body []byte, err error, attempt int, duration time.Duration) {
+	entry := &traceEntry{
+		Time:       time.Now(),
+		Type:       "response",
+		Attempt:    attempt,
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		tflog.SubsystemDebug(ctx, logSubsystem, "Request to Kibana failed", map[string]interface{}{
+			"method":      req.Method,
+			"url":         entry.URL,
+			"attempt":     attempt,
+			"duration_ms": entry.DurationMs,
+			"error":       entry.Error,
+		})
+	} else {
+		entry.StatusCode = resp.StatusCode
+		entry.Headers = redactHeaders(resp.Header)
+		entry.Body = redactBody(body)
+		tflog.SubsystemDebug(ctx, logSubsystem, "Received response from Kibana", map[string]interface{}{
+			"method":      req.Method,
+			"url":         entry.URL,
+			"attempt":     attempt,
+			"status_code": resp.StatusCode,
+			"duration_ms": entry.DurationMs,
+		})
+		tflog.SubsystemTrace(ctx, logSubsystem, "Response details", map[string]interface{}{
+			"status_code": resp.StatusCode,
+			"headers":     entry.Headers,
+			"body":        entry.Body,
+		})
+	}
+	c.trace.write(entry)
+}
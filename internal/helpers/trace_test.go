@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	body := `{"name":"rule","actions":[{"action_type_id":".webhook","params":{"url":"https://hooks.example.com","password":"hunter2","headers":{"Authorization":"Bearer abc"},"api_key":"k3y"}}]}`
+	result := redactBody([]byte(body))
+	for _, secret := range []string{"hunter2", "Bearer abc", "k3y"} {
+		if strings.Contains(result, secret) {
+			t.Errorf("Expected '%s' to be masked, got: %s", secret, result)
+		}
+	}
+	for _, value := range []string{"https://hooks.example.com", ".webhook", `"name":"rule"`} {
+		if !strings.Contains(result, value) {
+			t.Errorf("Expected '%s' to be kept, got: %s", value, result)
+		}
+	}
+
+	if result := redactBody([]byte("not json")); result != "not json" {
+		t.Errorf("Expected a body which is not JSON to be kept, got: %s", result)
+	}
+}
+
+func TestClientTraceFile(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"1","token":"response-secret"}`))
+	}))
+	defer svr.Close()
+
+	traceFile := filepath.Join(t.TempDir(), "trace.jsonl")
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "c2VjcmV0", TraceFile: traceFile})
+	if err := client.Post(context.Background(), "/detection_engine/rules", map[string]string{"name": "rule"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []traceEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "c2VjcmV0") || strings.Contains(line, "response-secret") {
+			t.Errorf("Expected secrets to be masked in the trace file, got: %s", line)
+		}
+		var entry traceEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 || entries[0].Type != "request" || entries[1].Type != "response" {
+		t.Fatalf("Expected a request and a response entry, got: %+v", entries)
+	}
+	if entries[0].Headers["Authorization"] != redacted || entries[0].Body != `{"name":"rule"}` {
+		t.Errorf("Unexpected request entry: %+v", entries[0])
+	}
+	if entries[1].StatusCode != 200 || !strings.Contains(entries[1].URL, "/api/detection_engine/rules") {
+		t.Errorf("Unexpected response entry: %+v", entries[1])
+	}
+}
@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a Kibana version, such as 8.11.3
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a version number. Pre-release suffixes such as "-SNAPSHOT" are ignored.
+func ParseVersion(s string) (*Version, error) {
+	number, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(s), "v"), "-")
+	parts := strings.Split(number, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version '%s'", s)
+	}
+	values := make([]int, 3)
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid version '%s'", s)
+		}
+		values[i] = value
+	}
+	return &Version{Major: values[0], Minor: values[1], Patch: values[2]}, nil
+}
+
+// MustParseVersion is like ParseVersion but panics when the version is invalid
+func MustParseVersion(s string) *Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v *Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or greater than other
+func (v *Version) Compare(other *Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is greater than or equal to other
+func (v *Version) AtLeast(other *Version) bool {
+	return v.Compare(other) >= 0
+}
+
+// statusResponse is the part of the /api/status response holding the version
+type statusResponse struct {
+	Version struct {
+		Number string `json:"number"`
+	} `json:"version"`
+}
+
+// DetectVersion reads the Kibana version from the status API and stores it on the client
+func (c *Client) DetectVersion(ctx context.Context) (*Version, error) {
+	var status statusResponse
+	if err := c.Get(ctx, "/status", &status); err != nil {
+		return nil, err
+	}
+	version, err := ParseVersion(status.Version.Number)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the Kibana version from the status API: %w", err)
+	}
+	c.version = version
+	return version, nil
+}
+
+// Version returns the Kibana version detected by DetectVersion, or nil when it is unknown
+func (c *Client) Version() *Version {
+	return c.version
+}
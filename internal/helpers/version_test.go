@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	for input, expected := range map[string]string{
+		"8.11.3":          "8.11.3",
+		"9.0.0-SNAPSHOT":  "9.0.0",
+		"7.17":            "7.17.0",
+		" v8.10.1 ":       "8.10.1",
+		"8.14.0-rc1-beta": "8.14.0",
+	} {
+		v, err := ParseVersion(input)
+		if err != nil || v.String() != expected {
+			t.Errorf("Expected '%s' to be parsed as %s, got %v (%v)", input, expected, v, err)
+		}
+	}
+	for _, input := range []string{"", "8", "8.x.0", "8.1.2.3", "8.-1.0"} {
+		if _, err := ParseVersion(input); err == nil {
+			t.Errorf("Expected '%s' to be rejected", input)
+		}
+	}
+
+	if !MustParseVersion("8.11.0").AtLeast(MustParseVersion("8.10.4")) ||
+		MustParseVersion("8.9.2").AtLeast(MustParseVersion("8.10.0")) ||
+		!MustParseVersion("9.0.0").AtLeast(MustParseVersion("9.0.0")) ||
+		MustParseVersion("7.17.9").Compare(MustParseVersion("8.0.0")) != -1 {
+		t.Errorf("Unexpected version comparison")
+	}
+}
+
+func TestClientDetectVersion(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"name":"kibana","version":{"number":"8.11.3","build_snapshot":false}}`))
+	}))
+	defer svr.Close()
+
+	client := newTestClient(t, svr, NewClientInput{ApiKey: "key"})
+	if client.Version() != nil {
+		t.Errorf("Expected the version to be unknown before detection")
+	}
+	version, err := client.DetectVersion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.String() != "8.11.3" || client.Version() != version {
+		t.Errorf("Unexpected version: %v", version)
+	}
+	if client.WithSpace("security").Version() != version {
+		t.Errorf("Expected space clients to share the detected version")
+	}
+}
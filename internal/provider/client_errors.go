@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// addClientError reports a failed API request, with tailored diagnostics for the common Kibana errors.
+// The prefix identifies the operation and the resource, e.g. "[Create][DetectionRule]".
+func addClientError(diags *diag.Diagnostics, prefix string, err error) {
+	switch helpers.StatusCode(err) {
+	case http.StatusUnauthorized:
+		diags.AddError(prefix+" Unauthorized",
+			fmt.Sprintf("Kibana rejected the configured credentials. Check the provider authentication settings. Got error: \n%s", err))
+	case http.StatusForbidden:
+		diags.AddError(prefix+" Permission Denied",
+			fmt.Sprintf("The configured credentials are not allowed to perform this request. "+
+				"Make sure they have the required Security privileges in the Kibana space, "+
+				"the privileges data source can help checking them. Got error: \n%s", err))
+	case http.StatusConflict:
+		diags.AddError(prefix+" Conflict",
+			fmt.Sprintf("The object already exists in Kibana. Either import it into the Terraform state "+
+				"or change its identifier (rule_id, list_id or item_id). Got error: \n%s", err))
+	default:
+		diags.AddError(prefix+" Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	}
+}
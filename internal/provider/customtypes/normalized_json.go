@@ -0,0 +1,165 @@
+// Package customtypes contains the custom attribute types of the provider
+package customtypes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// setKeys are the arrays whose order does not matter to Kibana, at any depth of the content
+var setKeys = map[string]bool{
+	"author":       true,
+	"index":        true,
+	"os_types":     true,
+	"tags":         true,
+	"threat_index": true,
+}
+
+var (
+	_ basetypes.StringTypable                    = (*NormalizedJSONType)(nil)
+	_ basetypes.StringValuableWithSemanticEquals = (*NormalizedJSON)(nil)
+	_ xattr.ValidateableAttribute                = (*NormalizedJSON)(nil)
+)
+
+// NormalizedJSONType is a JSON encoded string attribute. Values which only differ by formatting, key order
+// or the order of set-like arrays (tags, index, ...) are semantically equal and do not produce a plan.
+type NormalizedJSONType struct {
+	basetypes.StringType
+}
+
+func (t NormalizedJSONType) String() string {
+	return "customtypes.NormalizedJSONType"
+}
+
+func (t NormalizedJSONType) ValueType(ctx context.Context) attr.Value {
+	return NormalizedJSON{}
+}
+
+func (t NormalizedJSONType) Equal(o attr.Type) bool {
+	other, ok := o.(NormalizedJSONType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t NormalizedJSONType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return NormalizedJSON{StringValue: in}, nil
+}
+
+func (t NormalizedJSONType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+	stringValuable, diags := t.ValueFromString(ctx, stringValue)
+	if diags.HasError() {
+		return nil, fmt.Errorf("unexpected error converting StringValue to StringValuable: %v", diags)
+	}
+	return stringValuable, nil
+}
+
+// NormalizedJSON is the value of a NormalizedJSONType attribute
+type NormalizedJSON struct {
+	basetypes.StringValue
+}
+
+// NewNormalizedJSONValue returns a known JSON value
+func NewNormalizedJSONValue(value string) NormalizedJSON {
+	return NormalizedJSON{StringValue: basetypes.NewStringValue(value)}
+}
+
+// NewNormalizedJSONNull returns a null JSON value
+func NewNormalizedJSONNull() NormalizedJSON {
+	return NormalizedJSON{StringValue: basetypes.NewStringNull()}
+}
+
+func (v NormalizedJSON) Type(ctx context.Context) attr.Type {
+	return NormalizedJSONType{}
+}
+
+func (v NormalizedJSON) Equal(o attr.Value) bool {
+	other, ok := o.(NormalizedJSON)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+// StringSemanticEquals reports whether both values hold the same JSON content
+func (v NormalizedJSON) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	newValue, ok := newValuable.(NormalizedJSON)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this issue to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+	return SemanticallyEqual(v.ValueString(), newValue.ValueString()), diags
+}
+
+// ValidateAttribute rejects strings which are not valid JSON
+func (v NormalizedJSON) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+	if !json.Valid([]byte(v.ValueString())) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid JSON String Value",
+			"A string value was provided that is not valid JSON.\n\n"+
+				"Path: "+req.Path.String()+"\n"+
+				"Given Value: "+v.ValueString()+"\n",
+		)
+	}
+}
+
+// SemanticallyEqual reports whether two JSON strings hold the same content, ignoring the formatting,
+// the key order and the order of set-like arrays. Invalid JSON is compared as is.
+func SemanticallyEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalize(va, false), normalize(vb, false))
+}
+
+// normalize sorts the set-like arrays of a decoded JSON value
+func normalize(value interface{}, isSet bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item, setKeys[key])
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item, false)
+		}
+		if isSet {
+			sort.SliceStable(v, func(i, j int) bool {
+				ei, _ := json.Marshal(v[i])
+				ej, _ := json.Marshal(v[j])
+				return string(ei) < string(ej)
+			})
+		}
+	}
+	return value
+}
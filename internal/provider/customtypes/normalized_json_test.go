@@ -0,0 +1,65 @@
+package customtypes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+func TestSemanticallyEqual(t *testing.T) {
+	equal := map[string]string{
+		`{"name":"rule","risk_score":21}`:                                                            "{\n  \"risk_score\": 21,\n  \"name\": \"rule\"\n}",
+		`{"tags":["b","a"],"index":["logs-*","auditbeat-*"],"author":["x","y"]}`:                     `{"author":["y","x"],"index":["auditbeat-*","logs-*"],"tags":["a","b"]}`,
+		`{"threat":[{"framework":"MITRE ATT&CK","tactic":{"id":"TA0001","name":"Initial Access"}}]}`: `{"threat":[{"tactic":{"name":"Initial Access","id":"TA0001"},"framework":"MITRE ATT&CK"}]}`,
+		`{"entries":[{"field":"host.name","tags":["b","a"]}]}`:                                       `{"entries":[{"tags":["a","b"],"field":"host.name"}]}`,
+		`{"risk_score":21}`: `{"risk_score":21.0}`,
+	}
+	for a, b := range equal {
+		if !SemanticallyEqual(a, b) {
+			t.Errorf("Expected %s and %s to be equal", a, b)
+		}
+	}
+
+	different := map[string]string{
+		`{"name":"rule"}`:                                  `{"name":"rule","enabled":true}`,
+		`{"references":["https://a","https://b"]}`:         `{"references":["https://b","https://a"]}`,
+		`{"threat":[{"framework":"a"},{"framework":"b"}]}`: `{"threat":[{"framework":"b"},{"framework":"a"}]}`,
+		`{"tags":["a","a"]}`:                               `{"tags":["a"]}`,
+		`not json`:                                         `"not json"`,
+	}
+	for a, b := range different {
+		if SemanticallyEqual(a, b) {
+			t.Errorf("Expected %s and %s to be different", a, b)
+		}
+	}
+}
+
+func TestNormalizedJSONSemanticEquals(t *testing.T) {
+	ctx := context.Background()
+	equal, diags := NewNormalizedJSONValue(`{"a":1,"tags":["x","y"]}`).StringSemanticEquals(ctx, NewNormalizedJSONValue(`{"tags":["y","x"], "a": 1}`))
+	if diags.HasError() || !equal {
+		t.Errorf("Expected the values to be semantically equal, got %v (%v)", equal, diags)
+	}
+
+	if _, diags := NewNormalizedJSONValue(`{}`).StringSemanticEquals(ctx, basetypes.NewStringValue(`{}`)); !diags.HasError() {
+		t.Errorf("Expected an error for another value type")
+	}
+}
+
+func TestNormalizedJSONValidateAttribute(t *testing.T) {
+	ctx := context.Background()
+	for value, valid := range map[NormalizedJSON]bool{
+		NewNormalizedJSONValue(`{"name":"rule"}`): true,
+		NewNormalizedJSONNull():                   true,
+		NewNormalizedJSONValue(`{invalid_json}`):  false,
+	} {
+		var resp xattr.ValidateAttributeResponse
+		value.ValidateAttribute(ctx, xattr.ValidateAttributeRequest{Path: path.Root("rule_content")}, &resp)
+		if resp.Diagnostics.HasError() == valid {
+			t.Errorf("Value %s: expected valid=%v, got %v", value, valid, resp.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,794 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/customtypes"
+	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// The typed attributes and blocks of the detection rule, an alternative to rule_content.
+// Each of them maps to the rule field of the same name.
+
+type detectionRuleThreatEntryModel struct {
+	ID        types.String `tfsdk:"id"`
+	Name      types.String `tfsdk:"name"`
+	Reference types.String `tfsdk:"reference"`
+}
+
+type detectionRuleTechniqueModel struct {
+	ID           types.String                    `tfsdk:"id"`
+	Name         types.String                    `tfsdk:"name"`
+	Reference    types.String                    `tfsdk:"reference"`
+	Subtechnique []detectionRuleThreatEntryModel `tfsdk:"subtechnique"`
+}
+
+type detectionRuleThreatModel struct {
+	Framework types.String                   `tfsdk:"framework"`
+	Tactic    *detectionRuleThreatEntryModel `tfsdk:"tactic"`
+	Technique []detectionRuleTechniqueModel  `tfsdk:"technique"`
+}
+
+type detectionRuleCardinalityModel struct {
+	Field types.String `tfsdk:"field"`
+	Value types.Int64  `tfsdk:"value"`
+}
+
+type detectionRuleThresholdModel struct {
+	Field       types.List                      `tfsdk:"field"`
+	Value       types.Int64                     `tfsdk:"value"`
+	Cardinality []detectionRuleCardinalityModel `tfsdk:"cardinality"`
+}
+
+type detectionRuleThreatMappingEntryModel struct {
+	Field types.String `tfsdk:"field"`
+	Type  types.String `tfsdk:"type"`
+	Value types.String `tfsdk:"value"`
+}
+
+type detectionRuleThreatMappingModel struct {
+	Entries []detectionRuleThreatMappingEntryModel `tfsdk:"entries"`
+}
+
+type detectionRuleActionFrequencyModel struct {
+	Summary    types.Bool   `tfsdk:"summary"`
+	NotifyWhen types.String `tfsdk:"notify_when"`
+	Throttle   types.String `tfsdk:"throttle"`
+}
+
+type detectionRuleActionModel struct {
+	ActionTypeID types.String                       `tfsdk:"action_type_id"`
+	Group        types.String                       `tfsdk:"group"`
+	ID           types.String                       `tfsdk:"id"`
+	Params       customtypes.NormalizedJSON         `tfsdk:"params"`
+	Frequency    *detectionRuleActionFrequencyModel `tfsdk:"frequency"`
+}
+
+type detectionRuleRiskScoreMappingModel struct {
+	Field    types.String `tfsdk:"field"`
+	Operator types.String `tfsdk:"operator"`
+	Value    types.String `tfsdk:"value"`
+}
+
+type detectionRuleSeverityMappingModel struct {
+	Field    types.String `tfsdk:"field"`
+	Operator types.String `tfsdk:"operator"`
+	Severity types.String `tfsdk:"severity"`
+	Value    types.String `tfsdk:"value"`
+}
+
+// detectionRuleAttributes returns the typed attributes of the rule
+func detectionRuleAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"name": schema.StringAttribute{
+			MarkdownDescription: "The name of the rule. Conflicts with the `name` field of `rule_content`",
+			Optional:            true,
+		},
+		"description": schema.StringAttribute{
+			MarkdownDescription: "The description of the rule. Conflicts with the `description` field of `rule_content`",
+			Optional:            true,
+		},
+		"type": schema.StringAttribute{
+			MarkdownDescription: "The type of the rule, such as `query`, `eql`, `esql`, `threshold`, `threat_match`, `machine_learning` or `new_terms`. Conflicts with the `type` field of `rule_content`",
+			Optional:            true,
+		},
+		"query": schema.StringAttribute{
+			MarkdownDescription: "The query of the rule. Conflicts with the `query` field of `rule_content`",
+			Optional:            true,
+		},
+		"language": schema.StringAttribute{
+			MarkdownDescription: "The language of the query, such as `kuery`, `lucene`, `eql` or `esql`. Conflicts with the `language` field of `rule_content`",
+			Optional:            true,
+		},
+		"index": schema.ListAttribute{
+			MarkdownDescription: "The index patterns searched by the rule. Conflicts with the `index` field of `rule_content`",
+			ElementType:         types.StringType,
+			Optional:            true,
+		},
+		"severity": schema.StringAttribute{
+			MarkdownDescription: "The severity of the alerts: `low`, `medium`, `high` or `critical`. Conflicts with the `severity` field of `rule_content`",
+			Optional:            true,
+		},
+		"risk_score": schema.Int64Attribute{
+			MarkdownDescription: "The risk score of the alerts, from 0 to 100. Conflicts with the `risk_score` field of `rule_content`",
+			Optional:            true,
+		},
+		"tags": schema.ListAttribute{
+			MarkdownDescription: "The tags of the rule. Conflicts with the `tags` field of `rule_content`",
+			ElementType:         types.StringType,
+			Optional:            true,
+		},
+	}
+}
+
+// threatEntryAttributes are the attributes of a MITRE ATT&CK tactic, technique or subtechnique
+func threatEntryAttributes(object string) map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"id": schema.StringAttribute{
+			MarkdownDescription: "The " + object + " identifier, e.g. `TA0001`",
+			Optional:            true,
+		},
+		"name": schema.StringAttribute{
+			MarkdownDescription: "The " + object + " name",
+			Optional:            true,
+		},
+		"reference": schema.StringAttribute{
+			MarkdownDescription: "The " + object + " reference URL",
+			Optional:            true,
+		},
+	}
+}
+
+// detectionRuleBlocks returns the typed nested blocks of the rule
+func detectionRuleBlocks() map[string]schema.Block {
+	return map[string]schema.Block{
+		"threat": schema.ListNestedBlock{
+			MarkdownDescription: "The MITRE ATT&CK mapping of the rule. Conflicts with the `threat` field of `rule_content`",
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"framework": schema.StringAttribute{
+						MarkdownDescription: "The framework, e.g. `MITRE ATT&CK`",
+						Optional:            true,
+					},
+				},
+				Blocks: map[string]schema.Block{
+					"tactic": schema.SingleNestedBlock{
+						MarkdownDescription: "The tactic",
+						Attributes:          threatEntryAttributes("tactic"),
+					},
+					"technique": schema.ListNestedBlock{
+						MarkdownDescription: "The techniques",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: threatEntryAttributes("technique"),
+							Blocks: map[string]schema.Block{
+								"subtechnique": schema.ListNestedBlock{
+									MarkdownDescription: "The subtechniques",
+									NestedObject: schema.NestedBlockObject{
+										Attributes: threatEntryAttributes("subtechnique"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"threshold": schema.SingleNestedBlock{
+			MarkdownDescription: "The threshold of a `threshold` rule. Conflicts with the `threshold` field of `rule_content`",
+			Attributes: map[string]schema.Attribute{
+				"field": schema.ListAttribute{
+					MarkdownDescription: "The fields the events are grouped by",
+					ElementType:         types.StringType,
+					Optional:            true,
+				},
+				"value": schema.Int64Attribute{
+					MarkdownDescription: "The number of events of a group creating an alert",
+					Optional:            true,
+				},
+			},
+			Blocks: map[string]schema.Block{
+				"cardinality": schema.ListNestedBlock{
+					MarkdownDescription: "The minimum number of unique values of a field in a group",
+					NestedObject: schema.NestedBlockObject{
+						Attributes: map[string]schema.Attribute{
+							"field": schema.StringAttribute{
+								MarkdownDescription: "The field",
+								Optional:            true,
+							},
+							"value": schema.Int64Attribute{
+								MarkdownDescription: "The number of unique values",
+								Optional:            true,
+							},
+						},
+					},
+				},
+			},
+		},
+		"threat_mapping": schema.ListNestedBlock{
+			MarkdownDescription: "The mapping between source events and threat indicators of a `threat_match` rule. Conflicts with the `threat_mapping` field of `rule_content`",
+			NestedObject: schema.NestedBlockObject{
+				Blocks: map[string]schema.Block{
+					"entries": schema.ListNestedBlock{
+						MarkdownDescription: "The fields which must all match",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: map[string]schema.Attribute{
+								"field": schema.StringAttribute{
+									MarkdownDescription: "The field of the source event",
+									Optional:            true,
+								},
+								"type": schema.StringAttribute{
+									MarkdownDescription: "The type of the mapping, `mapping`",
+									Optional:            true,
+								},
+								"value": schema.StringAttribute{
+									MarkdownDescription: "The field of the threat indicator",
+									Optional:            true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"actions": schema.ListNestedBlock{
+			MarkdownDescription: "The actions run when the rule creates alerts. Conflicts with the `actions` field of `rule_content`",
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"action_type_id": schema.StringAttribute{
+						MarkdownDescription: "The connector type, e.g. `.slack` or `.webhook`",
+						Optional:            true,
+					},
+					"group": schema.StringAttribute{
+						MarkdownDescription: "The action group, usually `default`",
+						Optional:            true,
+					},
+					"id": schema.StringAttribute{
+						MarkdownDescription: "The connector identifier",
+						Optional:            true,
+					},
+					"params": schema.StringAttribute{
+						MarkdownDescription: "The parameters of the action (JSON encoded string)",
+						CustomType:          customtypes.NormalizedJSONType{},
+						Optional:            true,
+					},
+				},
+				Blocks: map[string]schema.Block{
+					"frequency": schema.SingleNestedBlock{
+						MarkdownDescription: "When the action runs",
+						Attributes: map[string]schema.Attribute{
+							"summary": schema.BoolAttribute{
+								MarkdownDescription: "Whether a summary of the alerts is sent instead of one action per alert",
+								Optional:            true,
+							},
+							"notify_when": schema.StringAttribute{
+								MarkdownDescription: "`onActiveAlert`, `onThrottleInterval` or `onActionGroupChange`",
+								Optional:            true,
+							},
+							"throttle": schema.StringAttribute{
+								MarkdownDescription: "The minimum time between actions with `onThrottleInterval`, e.g. `1h`",
+								Optional:            true,
+							},
+						},
+					},
+				},
+			},
+		},
+		"risk_score_mapping": schema.ListNestedBlock{
+			MarkdownDescription: "Overrides the risk score with the value of a source event field. Conflicts with the `risk_score_mapping` field of `rule_content`",
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"field": schema.StringAttribute{
+						MarkdownDescription: "The source event field",
+						Optional:            true,
+					},
+					"operator": schema.StringAttribute{
+						MarkdownDescription: "The operator, `equals`",
+						Optional:            true,
+					},
+					"value": schema.StringAttribute{
+						MarkdownDescription: "The value, usually empty",
+						Optional:            true,
+					},
+				},
+			},
+		},
+		"severity_mapping": schema.ListNestedBlock{
+			MarkdownDescription: "Overrides the severity when a source event field has a given value. Conflicts with the `severity_mapping` field of `rule_content`",
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"field": schema.StringAttribute{
+						MarkdownDescription: "The source event field",
+						Optional:            true,
+					},
+					"operator": schema.StringAttribute{
+						MarkdownDescription: "The operator, `equals`",
+						Optional:            true,
+					},
+					"severity": schema.StringAttribute{
+						MarkdownDescription: "The severity of the alert when the field has the value",
+						Optional:            true,
+					},
+					"value": schema.StringAttribute{
+						MarkdownDescription: "The value of the field",
+						Optional:            true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// typedListBlocks lists the typed list blocks, whose missing blocks are empty lists instead of null
+var typedListBlocks = map[string]bool{
+	"threat":             true,
+	"threat_mapping":     true,
+	"actions":            true,
+	"risk_score_mapping": true,
+	"severity_mapping":   true,
+}
+
+// isSet reports whether the typed attribute or block of a rule field is configured. Unknown values are configured,
+// and so are empty list attributes such as tags = [], only an empty list block is not configured.
+func isSet(field string, value attr.Value) bool {
+	if value.IsNull() {
+		return false
+	}
+	if list, ok := value.(types.List); ok && !list.IsUnknown() && typedListBlocks[field] {
+		return len(list.Elements()) > 0
+	}
+	return true
+}
+
+// typedFields returns the typed attributes and blocks of the model by rule field name
+func (m *DetectionRuleResourceModel) typedFields() map[string]attr.Value {
+	return map[string]attr.Value{
+		"name":               m.Name,
+		"description":        m.Description,
+		"type":               m.Type,
+		"query":              m.Query,
+		"language":           m.Language,
+		"index":              m.Index,
+		"severity":           m.Severity,
+		"risk_score":         m.RiskScore,
+		"tags":               m.Tags,
+		"threat":             m.Threat,
+		"threshold":          m.Threshold,
+		"threat_mapping":     m.ThreatMapping,
+		"actions":            m.Actions,
+		"risk_score_mapping": m.RiskScoreMapping,
+		"severity_mapping":   m.SeverityMapping,
+	}
+}
+
+// setTypedFields returns the rule fields configured through typed attributes and blocks
+func (m *DetectionRuleResourceModel) setTypedFields() []string {
+	var fields []string
+	for field, value := range m.typedFields() {
+		if isSet(field, value) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// usesTypedFields reports whether any typed attribute or block is configured
+func (m *DetectionRuleResourceModel) usesTypedFields() bool {
+	return len(m.setTypedFields()) > 0
+}
+
+// ruleContentFields returns the fields of rule_content, or nil when it is not set or not known yet
+func (m *DetectionRuleResourceModel) ruleContentFields() (map[string]json.RawMessage, error) {
+	if m.RuleContent.IsNull() || m.RuleContent.IsUnknown() {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(m.RuleContent.ValueString()), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// checkRuleFieldConflicts adds an error for every field set both in rule_content and through a typed attribute or block
+func (m *DetectionRuleResourceModel) checkRuleFieldConflicts(diags *diag.Diagnostics, prefix string) {
+	contentFields, err := m.ruleContentFields()
+	if err != nil {
+		diags.AddAttributeError(path.Root("rule_content"), prefix+" Parser Error", fmt.Sprintf("Unable to parse rule_content, got error: %s", err))
+		return
+	}
+	for _, field := range m.setTypedFields() {
+		if _, ok := contentFields[field]; ok {
+			diags.AddAttributeError(
+				path.Root(field),
+				prefix+" Conflicting Rule Field",
+				fmt.Sprintf("The field '%s' is set both in rule_content and as an attribute of the resource. Set it only once.", field),
+			)
+		}
+	}
+}
+
+// mergedRuleContent returns the JSON content of the rule, made of rule_content and the typed attributes and blocks.
+// All the values must be known.
+func (m *DetectionRuleResourceModel) mergedRuleContent(ctx context.Context, prefix string) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	m.checkRuleFieldConflicts(&diags, prefix)
+	if diags.HasError() {
+		return "", diags
+	}
+	if !m.usesTypedFields() {
+		return m.RuleContent.ValueString(), diags
+	}
+
+	fields, _ := m.ruleContentFields()
+	if fields == nil {
+		fields = map[string]json.RawMessage{}
+	}
+	values := m.typedValues(ctx, &diags)
+	if diags.HasError() {
+		return "", diags
+	}
+	for field, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			diags.AddAttributeError(path.Root(field), prefix+" Parser Error", fmt.Sprintf("Unable to encode %s, got error: %s", field, err))
+			return "", diags
+		}
+		fields[field] = raw
+	}
+	content, err := json.Marshal(fields)
+	if err != nil {
+		diags.AddError(prefix+" Parser Error", fmt.Sprintf("Unable to encode the rule content, got error: %s", err))
+		return "", diags
+	}
+	return string(content), diags
+}
+
+// typedValues converts the configured typed attributes and blocks to their rule field values
+func (m *DetectionRuleResourceModel) typedValues(ctx context.Context, diags *diag.Diagnostics) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, s := range []struct {
+		field string
+		value types.String
+	}{
+		{"name", m.Name}, {"description", m.Description}, {"type", m.Type},
+		{"query", m.Query}, {"language", m.Language}, {"severity", m.Severity},
+	} {
+		if !s.value.IsNull() {
+			values[s.field] = s.value.ValueString()
+		}
+	}
+	if !m.RiskScore.IsNull() {
+		values["risk_score"] = m.RiskScore.ValueInt64()
+	}
+	for _, l := range []struct {
+		field string
+		value types.List
+	}{
+		{"index", m.Index}, {"tags", m.Tags},
+	} {
+		if !l.value.IsNull() {
+			elements := []string{}
+			diags.Append(l.value.ElementsAs(ctx, &elements, false)...)
+			values[l.field] = elements
+		}
+	}
+
+	if isSet("threat", m.Threat) {
+		var threats []detectionRuleThreatModel
+		diags.Append(m.Threat.ElementsAs(ctx, &threats, false)...)
+		items := []transferobjects.ThreatItem{}
+		for _, threat := range threats {
+			item := transferobjects.ThreatItem{Framework: threat.Framework.ValueString()}
+			if threat.Tactic != nil {
+				item.Tactic = threatEntryFromModel(*threat.Tactic)
+			}
+			for _, technique := range threat.Technique {
+				t := transferobjects.ThreatTechnique{ThreatEntry: threatEntryFromModel(detectionRuleThreatEntryModel{
+					ID: technique.ID, Name: technique.Name, Reference: technique.Reference,
+				})}
+				for _, subtechnique := range technique.Subtechnique {
+					t.Subtechnique = append(t.Subtechnique, threatEntryFromModel(subtechnique))
+				}
+				item.Technique = append(item.Technique, t)
+			}
+			items = append(items, item)
+		}
+		values["threat"] = items
+	}
+
+	if isSet("threshold", m.Threshold) {
+		var threshold detectionRuleThresholdModel
+		diags.Append(m.Threshold.As(ctx, &threshold, basetypes.ObjectAsOptions{})...)
+		value := transferobjects.RuleThreshold{Value: int(threshold.Value.ValueInt64())}
+		if !threshold.Field.IsNull() {
+			diags.Append(threshold.Field.ElementsAs(ctx, &value.Field, false)...)
+		}
+		for _, cardinality := range threshold.Cardinality {
+			value.Cardinality = append(value.Cardinality, transferobjects.ThresholdCardinality{
+				Field: cardinality.Field.ValueString(),
+				Value: int(cardinality.Value.ValueInt64()),
+			})
+		}
+		values["threshold"] = value
+	}
+
+	if isSet("threat_mapping", m.ThreatMapping) {
+		var mappings []detectionRuleThreatMappingModel
+		diags.Append(m.ThreatMapping.ElementsAs(ctx, &mappings, false)...)
+		items := []transferobjects.ThreatMapping{}
+		for _, mapping := range mappings {
+			item := transferobjects.ThreatMapping{}
+			for _, entry := range mapping.Entries {
+				item.Entries = append(item.Entries, transferobjects.ThreatMappingEntry{
+					Field: entry.Field.ValueString(),
+					Type:  entry.Type.ValueString(),
+					Value: entry.Value.ValueString(),
+				})
+			}
+			items = append(items, item)
+		}
+		values["threat_mapping"] = items
+	}
+
+	if isSet("actions", m.Actions) {
+		var actions []detectionRuleActionModel
+		diags.Append(m.Actions.ElementsAs(ctx, &actions, false)...)
+		items := []transferobjects.ActionItem{}
+		for i, action := range actions {
+			item := transferobjects.ActionItem{
+				ActionTypeID: action.ActionTypeID.ValueString(),
+				Group:        action.Group.ValueString(),
+				ID:           action.ID.ValueString(),
+			}
+			if !action.Params.IsNull() {
+				if err := json.Unmarshal([]byte(action.Params.ValueString()), &item.Params); err != nil {
+					diags.AddAttributeError(
+						path.Root("actions").AtListIndex(i).AtName("params"),
+						"Invalid Action Params",
+						fmt.Sprintf("params must be a JSON encoded object, got error: %s", err),
+					)
+				}
+			}
+			if action.Frequency != nil {
+				item.Frequency = &transferobjects.ActionFrequency{
+					Summary:    action.Frequency.Summary.ValueBoolPointer(),
+					NotifyWhen: action.Frequency.NotifyWhen.ValueString(),
+					Throttle:   action.Frequency.Throttle.ValueStringPointer(),
+				}
+			}
+			items = append(items, item)
+		}
+		values["actions"] = items
+	}
+
+	if isSet("risk_score_mapping", m.RiskScoreMapping) {
+		var mappings []detectionRuleRiskScoreMappingModel
+		diags.Append(m.RiskScoreMapping.ElementsAs(ctx, &mappings, false)...)
+		items := []transferobjects.RiskScoreMapping{}
+		for _, mapping := range mappings {
+			items = append(items, transferobjects.RiskScoreMapping{
+				Field:    mapping.Field.ValueString(),
+				Operator: mapping.Operator.ValueString(),
+				Value:    mapping.Value.ValueString(),
+			})
+		}
+		values["risk_score_mapping"] = items
+	}
+
+	if isSet("severity_mapping", m.SeverityMapping) {
+		var mappings []detectionRuleSeverityMappingModel
+		diags.Append(m.SeverityMapping.ElementsAs(ctx, &mappings, false)...)
+		items := []transferobjects.SeverityMapping{}
+		for _, mapping := range mappings {
+			items = append(items, transferobjects.SeverityMapping{
+				Field:    mapping.Field.ValueString(),
+				Operator: mapping.Operator.ValueString(),
+				Severity: mapping.Severity.ValueString(),
+				Value:    mapping.Value.ValueString(),
+			})
+		}
+		values["severity_mapping"] = items
+	}
+	return values
+}
+
+func threatEntryFromModel(entry detectionRuleThreatEntryModel) transferobjects.ThreatEntry {
+	return transferobjects.ThreatEntry{
+		ID:        entry.ID.ValueString(),
+		Name:      entry.Name.ValueString(),
+		Reference: entry.Reference.ValueString(),
+	}
+}
+
+// stringOrNull keeps empty strings sent by Kibana out of the state
+func stringOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(s)
+}
+
+// configuredString reads a string back like stringOrNull, but keeps the empty string of a configured attribute
+func configuredString(configured types.String, s string) types.String {
+	if !configured.IsNull() {
+		return types.StringValue(s)
+	}
+	return stringOrNull(s)
+}
+
+// orEmpty keeps a configured empty list, which Kibana leaves out of its responses
+func orEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func threatEntryToModel(entry transferobjects.ThreatEntry) detectionRuleThreatEntryModel {
+	return detectionRuleThreatEntryModel{
+		ID:        stringOrNull(entry.ID),
+		Name:      stringOrNull(entry.Name),
+		Reference: stringOrNull(entry.Reference),
+	}
+}
+
+// setTypedState refreshes the configured typed attributes and blocks of the state from the rule read from Kibana.
+// The attributes which are not configured are left empty, their fields are kept in rule_content.
+func (m *DetectionRuleResourceModel) setTypedState(ctx context.Context, state *tfsdk.State, rule *transferobjects.DetectionRule) diag.Diagnostics {
+	var diags diag.Diagnostics
+	set := func(field string, value interface{}) {
+		diags.Append(state.SetAttribute(ctx, path.Root(field), value)...)
+	}
+	for _, field := range m.setTypedFields() {
+		switch field {
+		case "name":
+			set(field, stringOrNull(rule.Name))
+		case "description":
+			set(field, stringOrNull(rule.Description))
+		case "type":
+			set(field, stringOrNull(rule.Type))
+		case "query":
+			set(field, stringOrNull(rule.Query))
+		case "language":
+			set(field, stringOrNull(rule.Language))
+		case "severity":
+			set(field, stringOrNull(rule.Severity))
+		case "risk_score":
+			set(field, types.Int64Value(int64(rule.RiskScore)))
+		case "index":
+			set(field, orEmpty(rule.Index))
+		case "tags":
+			set(field, orEmpty(rule.Tags))
+		case "threat":
+			var configured []detectionRuleThreatModel
+			diags.Append(m.Threat.ElementsAs(ctx, &configured, false)...)
+			threats := []detectionRuleThreatModel{}
+			for i, item := range rule.Threat {
+				// Missing nested blocks are empty lists, not null
+				threat := detectionRuleThreatModel{Framework: stringOrNull(item.Framework), Technique: []detectionRuleTechniqueModel{}}
+				// The tactic block stays null when it is not configured and Kibana has none
+				if i >= len(configured) || configured[i].Tactic != nil || item.Tactic != (transferobjects.ThreatEntry{}) {
+					tactic := threatEntryToModel(item.Tactic)
+					threat.Tactic = &tactic
+				}
+				for _, t := range item.Technique {
+					entry := threatEntryToModel(t.ThreatEntry)
+					technique := detectionRuleTechniqueModel{ID: entry.ID, Name: entry.Name, Reference: entry.Reference, Subtechnique: []detectionRuleThreatEntryModel{}}
+					for _, subtechnique := range t.Subtechnique {
+						technique.Subtechnique = append(technique.Subtechnique, threatEntryToModel(subtechnique))
+					}
+					threat.Technique = append(threat.Technique, technique)
+				}
+				threats = append(threats, threat)
+			}
+			set(field, threats)
+		case "threshold":
+			fields, d := types.ListValueFrom(ctx, types.StringType, rule.Threshold.Field)
+			diags.Append(d...)
+			threshold := detectionRuleThresholdModel{
+				Field:       fields,
+				Value:       types.Int64Value(int64(rule.Threshold.Value)),
+				Cardinality: []detectionRuleCardinalityModel{},
+			}
+			for _, cardinality := range rule.Threshold.Cardinality {
+				threshold.Cardinality = append(threshold.Cardinality, detectionRuleCardinalityModel{
+					Field: stringOrNull(cardinality.Field),
+					Value: types.Int64Value(int64(cardinality.Value)),
+				})
+			}
+			set(field, &threshold)
+		case "threat_mapping":
+			var configured []detectionRuleThreatMappingModel
+			diags.Append(m.ThreatMapping.ElementsAs(ctx, &configured, false)...)
+			mappings := []detectionRuleThreatMappingModel{}
+			for i, item := range rule.ThreatMapping {
+				mapping := detectionRuleThreatMappingModel{Entries: []detectionRuleThreatMappingEntryModel{}}
+				for j, entry := range item.Entries {
+					var c detectionRuleThreatMappingEntryModel
+					if i < len(configured) && j < len(configured[i].Entries) {
+						c = configured[i].Entries[j]
+					}
+					mapping.Entries = append(mapping.Entries, detectionRuleThreatMappingEntryModel{
+						Field: configuredString(c.Field, entry.Field),
+						Type:  configuredString(c.Type, entry.Type),
+						Value: configuredString(c.Value, entry.Value),
+					})
+				}
+				mappings = append(mappings, mapping)
+			}
+			set(field, mappings)
+		case "actions":
+			var configured []detectionRuleActionModel
+			diags.Append(m.Actions.ElementsAs(ctx, &configured, false)...)
+			actions := []detectionRuleActionModel{}
+			for i, item := range rule.Actions {
+				action := detectionRuleActionModel{
+					ActionTypeID: stringOrNull(item.ActionTypeID),
+					Group:        stringOrNull(item.Group),
+					ID:           stringOrNull(item.ID),
+					Params:       customtypes.NewNormalizedJSONNull(),
+				}
+				if item.Params != nil {
+					params, _ := helpers.JSONToString(item.Params)
+					action.Params = customtypes.NewNormalizedJSONValue(params)
+				}
+				// Kibana fills the frequency in when it is not configured
+				if i < len(configured) && configured[i].Frequency == nil {
+					item.Frequency = nil
+				}
+				if item.Frequency != nil {
+					action.Frequency = &detectionRuleActionFrequencyModel{
+						Summary:    types.BoolPointerValue(item.Frequency.Summary),
+						NotifyWhen: stringOrNull(item.Frequency.NotifyWhen),
+						Throttle:   types.StringPointerValue(item.Frequency.Throttle),
+					}
+				}
+				actions = append(actions, action)
+			}
+			set(field, actions)
+		case "risk_score_mapping":
+			var configured []detectionRuleRiskScoreMappingModel
+			diags.Append(m.RiskScoreMapping.ElementsAs(ctx, &configured, false)...)
+			mappings := []detectionRuleRiskScoreMappingModel{}
+			for i, item := range rule.RiskScoreMapping {
+				var c detectionRuleRiskScoreMappingModel
+				if i < len(configured) {
+					c = configured[i]
+				}
+				mappings = append(mappings, detectionRuleRiskScoreMappingModel{
+					Field:    configuredString(c.Field, item.Field),
+					Operator: configuredString(c.Operator, item.Operator),
+					Value:    configuredString(c.Value, item.Value),
+				})
+			}
+			set(field, mappings)
+		case "severity_mapping":
+			var configured []detectionRuleSeverityMappingModel
+			diags.Append(m.SeverityMapping.ElementsAs(ctx, &configured, false)...)
+			mappings := []detectionRuleSeverityMappingModel{}
+			for i, item := range rule.SeverityMapping {
+				var c detectionRuleSeverityMappingModel
+				if i < len(configured) {
+					c = configured[i]
+				}
+				mappings = append(mappings, detectionRuleSeverityMappingModel{
+					Field:    configuredString(c.Field, item.Field),
+					Operator: configuredString(c.Operator, item.Operator),
+					Severity: configuredString(c.Severity, item.Severity),
+					Value:    configuredString(c.Value, item.Value),
+				})
+			}
+			set(field, mappings)
+		}
+	}
+	return diags
+}
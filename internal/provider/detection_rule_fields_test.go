@@ -0,0 +1,292 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"terraform-provider-elastic-siem-detection/internal/provider/customtypes"
+	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newDetectionRuleState returns an empty state of the detection rule resource
+func newDetectionRuleState(t *testing.T) tfsdk.State {
+	t.Helper()
+	ctx := context.Background()
+	var schemaResp resource.SchemaResponse
+	NewDetectionRuleResource().Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	if diags := schemaResp.Schema.ValidateImplementation(ctx); diags.HasError() {
+		t.Fatalf("Invalid schema: %v", diags)
+	}
+	return tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}
+}
+
+// newDetectionRuleStateWith returns a detection rule state holding the values of the given attributes
+func newDetectionRuleStateWith(t *testing.T, values map[string]interface{}) tfsdk.State {
+	t.Helper()
+	state := newDetectionRuleState(t)
+	for field, value := range values {
+		if diags := state.SetAttribute(context.Background(), path.Root(field), value); diags.HasError() {
+			t.Fatalf("Unable to set %s: %v", field, diags)
+		}
+	}
+	return state
+}
+
+func TestDetectionRuleMergedRuleContent(t *testing.T) {
+	ctx := context.Background()
+	state := newDetectionRuleStateWith(t, map[string]interface{}{
+		"rule_content": `{"rule_id":"my_rule","enabled":true}`,
+		"name":         "My rule",
+		"risk_score":   int64(47),
+		"tags":         []string{"MITRE"},
+		"threat": []detectionRuleThreatModel{{
+			Framework: types.StringValue("MITRE ATT&CK"),
+			Tactic:    &detectionRuleThreatEntryModel{ID: types.StringValue("TA0001"), Name: types.StringValue("Initial Access"), Reference: types.StringNull()},
+			Technique: []detectionRuleTechniqueModel{{
+				ID: types.StringValue("T1133"), Name: types.StringNull(), Reference: types.StringNull(),
+				Subtechnique: []detectionRuleThreatEntryModel{},
+			}},
+		}},
+		"actions": []detectionRuleActionModel{{
+			ActionTypeID: types.StringValue(".webhook"),
+			Group:        types.StringValue("default"),
+			ID:           types.StringValue("connector"),
+			Params:       customtypes.NewNormalizedJSONValue(`{"body": "alert"}`),
+		}},
+	})
+
+	var data DetectionRuleResourceModel
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	content, diags := data.mergedRuleContent(ctx, "[Test]")
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	var rule transferobjects.DetectionRule
+	if err := json.Unmarshal([]byte(content), &rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.RuleID != "my_rule" || rule.Enabled == nil || rule.Name != "My rule" || rule.RiskScore != 47 || len(rule.Tags) != 1 {
+		t.Errorf("Expected rule_content and the typed attributes to be merged, got: %s", content)
+	}
+	if len(rule.Threat) != 1 || rule.Threat[0].Tactic.ID != "TA0001" || rule.Threat[0].Technique[0].ID != "T1133" {
+		t.Errorf("Unexpected threat: %+v", rule.Threat)
+	}
+	if len(rule.Actions) != 1 || rule.Actions[0].Params["body"] != "alert" || rule.Actions[0].Frequency != nil {
+		t.Errorf("Unexpected actions: %+v", rule.Actions)
+	}
+
+	// A field set twice is rejected
+	if diags := state.SetAttribute(ctx, path.Root("rule_content"), `{"rule_id":"my_rule","name":"Other name","tags":[]}`); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, diags := data.mergedRuleContent(ctx, "[Test]"); diags.ErrorsCount() != 2 {
+		t.Errorf("Expected name and tags to conflict, got: %v", diags)
+	}
+}
+
+func TestDetectionRuleSetTypedState(t *testing.T) {
+	ctx := context.Background()
+	state := newDetectionRuleStateWith(t, map[string]interface{}{
+		"name": "Old name",
+		"actions": []detectionRuleActionModel{{
+			ActionTypeID: types.StringValue(".webhook"),
+			Group:        types.StringValue("default"),
+			ID:           types.StringValue("connector"),
+			Params:       customtypes.NewNormalizedJSONValue(`{ "body": "alert" }`),
+		}},
+	})
+	var data DetectionRuleResourceModel
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	var rule transferobjects.DetectionRule
+	if err := json.Unmarshal([]byte(`{
+		"name": "New name",
+		"tags": ["not", "configured"],
+		"actions": [{"action_type_id": ".webhook", "group": "default", "id": "connector", "params": {"body": "alert"},
+			"frequency": {"summary": true, "notifyWhen": "onActiveAlert", "throttle": null}}]
+	}`), &rule); err != nil {
+		t.Fatal(err)
+	}
+	if diags := data.setTypedState(ctx, &state, &rule); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Name.ValueString() != "New name" {
+		t.Errorf("Expected the configured name to be refreshed, got %s", data.Name)
+	}
+	if !data.Tags.IsNull() {
+		t.Errorf("Expected the tags which are not configured to stay empty, got %s", data.Tags)
+	}
+	var actions []detectionRuleActionModel
+	data.Actions.ElementsAs(ctx, &actions, false)
+	if len(actions) != 1 || !customtypes.SemanticallyEqual(actions[0].Params.ValueString(), `{ "body": "alert" }`) || actions[0].Frequency != nil {
+		t.Errorf("Expected the same params and no frequency, got: %+v", actions)
+	}
+}
+
+func TestDetectionRuleSetTypedStateMappings(t *testing.T) {
+	ctx := context.Background()
+	state := newDetectionRuleStateWith(t, map[string]interface{}{
+		"risk_score_mapping": []detectionRuleRiskScoreMappingModel{{
+			Field: types.StringValue("event.risk_score"), Operator: types.StringValue("equals"), Value: types.StringValue(""),
+		}},
+		"severity_mapping": []detectionRuleSeverityMappingModel{{
+			Field: types.StringValue("event.severity"), Operator: types.StringValue("equals"), Severity: types.StringValue("low"), Value: types.StringValue(""),
+		}},
+		"threat": []detectionRuleThreatModel{{
+			Framework: types.StringValue("MITRE ATT&CK"),
+			Technique: []detectionRuleTechniqueModel{},
+		}},
+	})
+	var data DetectionRuleResourceModel
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	// Kibana requires the value, even when it is empty
+	content, diags := data.mergedRuleContent(ctx, "[Test]")
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !strings.Contains(content, `"risk_score_mapping":[{"field":"event.risk_score","operator":"equals","value":""}]`) ||
+		!strings.Contains(content, `"severity_mapping":[{"field":"event.severity","value":"","operator":"equals","severity":"low"}]`) {
+		t.Errorf("Expected the empty mapping values to be sent, got: %s", content)
+	}
+
+	var rule transferobjects.DetectionRule
+	if err := json.Unmarshal([]byte(content), &rule); err != nil {
+		t.Fatal(err)
+	}
+	if diags := data.setTypedState(ctx, &state, &rule); diags.HasError() {
+		t.Fatal(diags)
+	}
+	var read DetectionRuleResourceModel
+	if diags := state.Get(ctx, &read); diags.HasError() {
+		t.Fatal(diags)
+	}
+	for _, l := range []struct {
+		name             string
+		configured, read types.List
+	}{
+		{"risk_score_mapping", data.RiskScoreMapping, read.RiskScoreMapping},
+		{"severity_mapping", data.SeverityMapping, read.SeverityMapping},
+		{"threat", data.Threat, read.Threat},
+	} {
+		if !l.read.Equal(l.configured) {
+			t.Errorf("Expected %s to read back as configured %s, got %s", l.name, l.configured, l.read)
+		}
+	}
+}
+
+func TestDetectionRuleEmptyTags(t *testing.T) {
+	ctx := context.Background()
+	state := newDetectionRuleStateWith(t, map[string]interface{}{
+		"rule_content": `{"rule_id":"my_rule","name":"My rule"}`,
+		"tags":         []string{},
+	})
+	var data DetectionRuleResourceModel
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	// An empty list attribute is managed like any other value
+	if !slices.Contains(data.setTypedFields(), "tags") {
+		t.Errorf("Expected tags = [] to be set, got: %v", data.setTypedFields())
+	}
+	content, diags := data.mergedRuleContent(ctx, "[Test]")
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !strings.Contains(content, `"tags":[]`) {
+		t.Errorf("Expected the empty tags to be sent, got: %s", content)
+	}
+
+	// Kibana leaves out the empty tags
+	if diags := data.setTypedState(ctx, &state, &transferobjects.DetectionRule{Name: "My rule"}); diags.HasError() {
+		t.Fatal(diags)
+	}
+	var read DetectionRuleResourceModel
+	if diags := state.Get(ctx, &read); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !read.Tags.Equal(data.Tags) {
+		t.Errorf("Expected the tags to read back as %s, got %s", data.Tags, read.Tags)
+	}
+
+	// The tags cannot be set in rule_content as well
+	data.RuleContent = customtypes.NewNormalizedJSONValue(`{"rule_id":"my_rule","tags":["a"]}`)
+	var conflicts diag.Diagnostics
+	data.checkRuleFieldConflicts(&conflicts, "[Test]")
+	if conflicts.ErrorsCount() != 1 {
+		t.Errorf("Expected tags to conflict, got: %v", conflicts)
+	}
+}
+
+func TestDetectionRuleRoundTrip(t *testing.T) {
+	raw := []byte(`{"id":"a1b2c3","rule_id":"rule","type":"query",` +
+		`"alert_suppression":{"group_by":["host.name","user.name"],"duration":{"value":30,"unit":"m"},"missing_fields_strategy":"doNotSuppress"},` +
+		`"response_actions":[{"action_type_id":".endpoint","params":{"command":"isolate","comment":"Contained"}}],` +
+		`"namespace":"team-a","meta":{"from":"1m","kibana_siem_app_url":"https://kibana/app/security"},"revision":3,"rule_source":{"type":"internal"},` +
+		`"outcome":"exactMatch","custom_field":"configured",` +
+		`"created_at":"2024-05-01T10:00:00.000Z","execution_summary":{"last_execution":{"status":"succeeded"}}}`)
+	var response transferobjects.DetectionRuleResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := transferobjects.UnknownFields(raw, transferobjects.DetectionRule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := DetectionRuleResourceModel{RuleContent: customtypes.NewNormalizedJSONValue(`{"rule_id":"rule","type":"query","custom_field":"configured"}`)}
+	content, diags := data.ruleContentFromResponse(&response, unknown, nil, "[Test]")
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	expected := `{"alert_suppression":{"duration":{"unit":"m","value":30},"group_by":["host.name","user.name"],"missing_fields_strategy":"doNotSuppress"},` +
+		`"custom_field":"configured","meta":{"from":"1m"},"namespace":"team-a",` +
+		`"response_actions":[{"action_type_id":".endpoint","params":{"command":"isolate","comment":"Contained"}}],"rule_id":"rule","type":"query"}`
+	if content != expected {
+		t.Errorf("Expected %s, got %s", expected, content)
+	}
+}
+
+func TestRemoveReadOnlyRuleFields(t *testing.T) {
+	for content, expected := range map[string]string{
+		`{"name":"x","revision":3,"meta":{"kibana_siem_app_url":"https://kibana/app/security"}}`:           `{"name":"x"}`,
+		`{"name":"x","meta":{"from":"1m","kibana_siem_app_url":"https://kibana/app/security"}}`:            `{"meta":{"from":"1m"},"name":"x"}`,
+		`{"name":"x","meta":{"from":"1m"},"rule_source":{"type":"internal"},"execution_summary":{},"a":1}`: `{"a":1,"meta":{"from":"1m"},"name":"x"}`,
+	} {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(content), &fields); err != nil {
+			t.Fatal(err)
+		}
+		if err := removeReadOnlyRuleFields(fields); err != nil {
+			t.Fatal(err)
+		}
+		if actual, _ := json.Marshal(fields); string(actual) != expected {
+			t.Errorf("Expected %s, got %s", expected, actual)
+		}
+	}
+}
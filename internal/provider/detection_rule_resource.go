@@ -2,15 +2,22 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"slices"
 	"strings"
 	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/customtypes"
 	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
@@ -18,11 +25,19 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &DetectionRuleResource{}
 var _ resource.ResourceWithImportState = &DetectionRuleResource{}
+var _ resource.ResourceWithModifyPlan = &DetectionRuleResource{}
+var _ resource.ResourceWithValidateConfig = &DetectionRuleResource{}
 
 func NewDetectionRuleResource() resource.Resource {
 	return &DetectionRuleResource{}
 }
 
+// The update strategies of the detection rule
+const (
+	updateStrategyPut   = "put"
+	updateStrategyPatch = "patch"
+)
+
 // DetectionRuleResource defines the resource implementation.
 type DetectionRuleResource struct {
 	client *helpers.Client
@@ -30,8 +45,36 @@ type DetectionRuleResource struct {
 
 // DetectionRuleResourceModel describes the resource data model.
 type DetectionRuleResourceModel struct {
-	RuleContent types.String `tfsdk:"rule_content"`
-	Id          types.String `tfsdk:"id"`
+	RuleContent customtypes.NormalizedJSON `tfsdk:"rule_content"`
+	Id          types.String               `tfsdk:"id"`
+	SpaceID     types.String               `tfsdk:"space_id"`
+
+	UpdateStrategy types.String `tfsdk:"update_strategy"`
+
+	// Computed from the rule read from Kibana
+	LastExecutionStatus  types.String `tfsdk:"last_execution_status"`
+	LastExecutionDate    types.String `tfsdk:"last_execution_date"`
+	LastExecutionMessage types.String `tfsdk:"last_execution_message"`
+	Revision             types.Int64  `tfsdk:"revision"`
+	Version              types.Int64  `tfsdk:"version"`
+	KibanaURL            types.String `tfsdk:"kibana_url"`
+
+	// Typed alternative to rule_content, see detection_rule_fields.go
+	Name             types.String `tfsdk:"name"`
+	Description      types.String `tfsdk:"description"`
+	Type             types.String `tfsdk:"type"`
+	Query            types.String `tfsdk:"query"`
+	Language         types.String `tfsdk:"language"`
+	Index            types.List   `tfsdk:"index"`
+	Severity         types.String `tfsdk:"severity"`
+	RiskScore        types.Int64  `tfsdk:"risk_score"`
+	Tags             types.List   `tfsdk:"tags"`
+	Threat           types.List   `tfsdk:"threat"`
+	Threshold        types.Object `tfsdk:"threshold"`
+	ThreatMapping    types.List   `tfsdk:"threat_mapping"`
+	Actions          types.List   `tfsdk:"actions"`
+	RiskScoreMapping types.List   `tfsdk:"risk_score_mapping"`
+	SeverityMapping  types.List   `tfsdk:"severity_mapping"`
 }
 
 func (r *DetectionRuleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -39,24 +82,74 @@ func (r *DetectionRuleResource) Metadata(ctx context.Context, req resource.Metad
 }
 
 func (r *DetectionRuleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
-	resp.Schema = schema.Schema{
-		// This description is used by the documentation generator and the language server.
-		MarkdownDescription: "Detection rule resource",
-
-		Attributes: map[string]schema.Attribute{
-			"rule_content": schema.StringAttribute{
-				MarkdownDescription: "The content of the rule (JSON encoded string)",
-				Required:            true,
+	attributes := map[string]schema.Attribute{
+		"rule_content": schema.StringAttribute{
+			MarkdownDescription: "The content of the rule (JSON encoded string). Can be combined with the typed attributes and blocks, as long as a field is not set in both. Formatting, key order and the order of set-like arrays such as `tags` are ignored when comparing it. Fields holding the default filled in by Kibana, such as `max_signals` or `interval`, are only kept when they are set. Fields the provider does not model are sent as they are and read back when they are set or belong to the rule author, such as `namespace` and `meta`, except read-only fields such as `revision` or `meta.kibana_siem_app_url`. Changing the `rule_id` of the content forces a new resource",
+			CustomType:          customtypes.NormalizedJSONType{},
+			Optional:            true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplaceIf(
+					requiresReplaceIfRuleIDChanged,
+					"Changing the rule_id forces a new resource",
+					"Changing the `rule_id` forces a new resource",
+				),
+			},
+		},
+		"id": schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: "Rule identifier (in UUID format)",
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
 			},
-			"id": schema.StringAttribute{
-				Computed:            true,
-				MarkdownDescription: "Rule identifier (in UUID format)",
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.UseStateForUnknown(),
-				},
+		},
+		"space_id": spaceIDAttribute("rule"),
+		"update_strategy": schema.StringAttribute{
+			MarkdownDescription: "How the rule is updated: `put` replaces the whole rule, resetting the fields which are not configured, " +
+				"`patch` only sends the fields which changed and leaves the fields which are not configured, such as exceptions or actions added in Kibana, untouched. " +
+				"With `patch`, only the configured fields of `rule_content` are read back. Defaults to `put`",
+			Optional: true,
+			Computed: true,
+			Default:  stringdefault.StaticString(updateStrategyPut),
+		},
+		"last_execution_status": schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: "The status of the last execution of the rule, such as `succeeded`, `partial failure` or `failed`. Empty until the rule runs",
+		},
+		"last_execution_date": schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: "The date of the last execution of the rule (RFC 3339)",
+		},
+		"last_execution_message": schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: "The message of the last execution of the rule, explaining its failures",
+		},
+		"revision": schema.Int64Attribute{
+			Computed:            true,
+			MarkdownDescription: "The revision of the rule, increased by Kibana on every change",
+		},
+		"version": schema.Int64Attribute{
+			Computed:            true,
+			MarkdownDescription: "The version of the rule",
+		},
+		"kibana_url": schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: "The URL of the rule page in Kibana",
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
 			},
 		},
 	}
+	for name, attribute := range detectionRuleAttributes() {
+		attributes[name] = attribute
+	}
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Detection rule resource. The rule is described either by `rule_content` or by the typed attributes and blocks, or by a combination of both",
+
+		Attributes: attributes,
+		Blocks:     detectionRuleBlocks(),
+	}
 }
 
 func (r *DetectionRuleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -91,8 +184,15 @@ func (r *DetectionRuleResource) Create(ctx context.Context, req resource.CreateR
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Process the content
-	err := helpers.ObjectFromJSON(data.RuleContent.ValueString(), &body)
+	content, diags := data.mergedRuleContent(ctx, "[Create][DetectionRule]")
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	err := helpers.ObjectFromJSON(content, &body)
 	if err != nil {
 		resp.Diagnostics.AddError("[Create][DetectionRule] Parser Error", fmt.Sprintf("Unable to parse file, got error: %s", err))
 		return
@@ -107,15 +207,23 @@ func (r *DetectionRuleResource) Create(ctx context.Context, req resource.CreateR
 		itemsToRemove = append(itemsToRemove, "exceptions_list")
 	}
 
+	adaptDetectionRule(body, client.Version())
+	request, err := withUnknownRuleFields(body, content)
+	if err != nil {
+		resp.Diagnostics.AddError("[Create][DetectionRule] Parser Error", fmt.Sprintf("Unable to encode the rule, got error: %s", err))
+		return
+	}
+
 	// Create via API
 	var response transferobjects.DetectionRuleResponse
-	if err := r.client.Post("/detection_engine/rules", body, &response, itemsToRemove); err != nil {
-		resp.Diagnostics.AddError("[Create][DetectionRule] Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	if err := client.Post(ctx, "/detection_engine/rules", request, &response, itemsToRemove); err != nil {
+		addClientError(&resp.Diagnostics, "[Create][DetectionRule]", err)
 		return
 	}
 
 	// Save id into the Terraform state
 	data.Id = types.StringValue(response.ID)
+	data.setComputedAttributes(client, &response)
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -131,21 +239,63 @@ func (r *DetectionRuleResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
+	// Imported rules have no update strategy yet
+	if data.UpdateStrategy.IsNull() {
+		data.UpdateStrategy = types.StringValue(updateStrategyPut)
+	}
+
 	// Get via API
 	var response transferobjects.DetectionRuleResponse
 	path := fmt.Sprintf("/detection_engine/rules?id=%s", data.Id.ValueString())
-	if err := r.client.Get(path, &response); err != nil {
-		if strings.Contains(err.Error(), "404") {
+	unknown, err := getDetectionRule(ctx, client, path, &response)
+	// The rule may have been deleted and created again with the same rule_id, e.g. from the Kibana UI
+	if ruleID := ruleIDOf(data.RuleContent.ValueString()); helpers.IsNotFound(err) && ruleID != "" {
+		response = transferobjects.DetectionRuleResponse{}
+		if unknown, err = getDetectionRule(ctx, client, "/detection_engine/rules?rule_id="+url.QueryEscape(ruleID), &response); err == nil {
+			resp.Diagnostics.AddWarning(
+				"[Read][DetectionRule] Rule Adopted",
+				fmt.Sprintf("The rule %s no longer exists, the rule %s with the same rule_id '%s' is managed instead.", data.Id.ValueString(), response.ID, ruleID),
+			)
+			data.Id = types.StringValue(response.ID)
+		}
+	}
+	if err != nil {
+		if helpers.IsNotFound(err) {
 			resp.Diagnostics.AddWarning("[Read][DetectionRule] Client Error", fmt.Sprintf("Resource not found. Will try to recreate if needed. Got error: %s", err))
 			data.Id = types.StringNull()
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError("[Read][DetectionRule] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+			addClientError(&resp.Diagnostics, "[Read][DetectionRule]", err)
 			return
 		}
 	}
 
+	jsonStr, diags := data.ruleContentFromResponse(&response, unknown, client.Version(), "[Read][DetectionRule]")
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// rule_content stays empty when the rule is only described by typed attributes and blocks
+	if !data.RuleContent.IsNull() || !data.usesTypedFields() {
+		data.RuleContent = customtypes.NewNormalizedJSONValue(jsonStr)
+	}
+	data.setComputedAttributes(client, &response)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	resp.Diagnostics.Append(data.setTypedState(ctx, &resp.State, &response.DetectionRule)...)
+}
+
+// ruleContentFromResponse returns the rule_content of a rule read from Kibana, without the fields managed by Kibana,
+// the fields of the typed attributes and blocks and the server defaults which are not configured.
+// The unknown fields of the response, which the transfer object does not model, are kept unless they are read-only.
+func (m *DetectionRuleResourceModel) ruleContentFromResponse(response *transferobjects.DetectionRuleResponse, unknown map[string]json.RawMessage, version *helpers.Version, prefix string) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
 	// Remove immutable or deprecated objects
 	var itemsToRemove []string
 	itemsToRemove = append(itemsToRemove, "created_by")
@@ -175,17 +325,255 @@ func (r *DetectionRuleResource) Read(ctx context.Context, req resource.ReadReque
 		itemsToRemove = append(itemsToRemove, "timeline_title")
 	}
 
+	// Keep the investigation_fields format of the content, the server may answer with the other one
+	var previous *transferobjects.DetectionRule
+	if err := helpers.ObjectFromJSON(m.RuleContent.ValueString(), &previous); err == nil && previous != nil &&
+		previous.InvestigationFields != nil && response.InvestigationFields != nil {
+		response.InvestigationFields.SetLegacy(previous.InvestigationFields.IsLegacy())
+	}
+
+	// The fields of the typed attributes and blocks are not duplicated in rule_content
+	itemsToRemove = append(itemsToRemove, m.setTypedFields()...)
+
 	// Update the current state in case of diffs
-	jsonStr, err := helpers.JSONfromObject(response.DetectionRule, itemsToRemove)
+	unknown, err := managedUnknownRuleFields(unknown, m.RuleContent.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("[Read][DetectionRule] JSONfromObject Error", fmt.Sprintf("Error while JSONfromObject the updated state Rule Content, got error: %s", err))
-		return
+		diags.AddError(prefix+" Parser Error", fmt.Sprintf("Error while filtering the unknown fields of the Rule Content, got error: %s", err))
+		return "", diags
+	}
+	rule, err := withUnknownRuleFieldsOf(response.DetectionRule, unknown)
+	if err != nil {
+		diags.AddError(prefix+" Parser Error", fmt.Sprintf("Error while adding the unknown fields to the Rule Content, got error: %s", err))
+		return "", diags
+	}
+	jsonStr, err := helpers.JSONfromObject(rule, itemsToRemove)
+	if err != nil {
+		diags.AddError(prefix+" JSONfromObject Error", fmt.Sprintf("Error while JSONfromObject the updated state Rule Content, got error: %s", err))
+		return "", diags
 	}
 
-	data.RuleContent = types.StringValue(jsonStr)
+	// Leave out the defaults filled in by Kibana for the fields which are not configured
+	jsonStr, err = pruneServerDefaults(jsonStr, m.RuleContent.ValueString(), version, response.Type, detectionRuleDefaults)
+	if err != nil {
+		diags.AddError(prefix+" Server Defaults Error", fmt.Sprintf("Error while removing the server defaults from the Rule Content, got error: %s", err))
+		return "", diags
+	}
 
-	// Save updated data into Terraform state
-	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	// With the patch strategy, the fields which are not configured are managed outside of Terraform
+	if m.UpdateStrategy.ValueString() == updateStrategyPatch && !m.RuleContent.IsNull() {
+		if jsonStr, err = configuredFieldsOnly(jsonStr, m.RuleContent.ValueString()); err != nil {
+			diags.AddError(prefix+" Parser Error", fmt.Sprintf("Error while removing the fields which are not configured from the Rule Content, got error: %s", err))
+			return "", diags
+		}
+	}
+
+	return jsonStr, diags
+}
+
+// setComputedAttributes sets the attributes computed from the rule returned by Kibana
+func (m *DetectionRuleResourceModel) setComputedAttributes(client *helpers.Client, response *transferobjects.DetectionRuleResponse) {
+	lastExecution := response.ExecutionSummary.LastExecution
+	m.LastExecutionStatus = stringOrNull(lastExecution.Status)
+	m.LastExecutionMessage = stringOrNull(lastExecution.Message)
+	m.LastExecutionDate = types.StringNull()
+	if !lastExecution.Date.IsZero() {
+		m.LastExecutionDate = types.StringValue(lastExecution.Date.Format(time.RFC3339))
+	}
+	m.Revision = types.Int64Value(int64(response.Revision))
+	m.Version = types.Int64Value(int64(response.Version))
+
+	// The security app URL is only known by Kibana when the rule was saved from its UI
+	if appURL := response.Meta.KibanaSiemAppURL; appURL != "" {
+		m.KibanaURL = types.StringValue(strings.TrimSuffix(appURL, "/") + "/rules/id/" + url.PathEscape(m.Id.ValueString()))
+	} else {
+		m.KibanaURL = types.StringValue(client.SpaceURL("/app/security/rules/id/" + url.PathEscape(m.Id.ValueString())))
+	}
+}
+
+// readOnlyRuleFields is the denylist of the fields which Kibana computes, nested fields are separated by a dot.
+// They are left out of rule_content and of the requests even though the transfer object does not model them.
+var readOnlyRuleFields = []string{
+	"created_at",
+	"created_by",
+	"updated_at",
+	"updated_by",
+	"execution_summary",
+	"revision",
+	"rule_source",
+	"meta.kibana_siem_app_url",
+}
+
+// managedRuleFields is the allowlist of the fields which the transfer object does not model but which belong to the
+// rule author. They are always read back, so that their changes outside of Terraform show up as drift. The other
+// unknown fields are only read back when they are configured.
+var managedRuleFields = []string{
+	"namespace",
+	"meta",
+}
+
+// removeReadOnlyRuleFields removes the fields of the readOnlyRuleFields denylist, and the objects left empty by the
+// removal of their nested fields
+func removeReadOnlyRuleFields(fields map[string]json.RawMessage) error {
+	for _, field := range readOnlyRuleFields {
+		parent, name, nested := strings.Cut(field, ".")
+		if !nested {
+			delete(fields, field)
+			continue
+		}
+		raw, ok := fields[parent]
+		if !ok {
+			continue
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil || object == nil {
+			// Not an object, there is nothing nested to remove
+			continue
+		}
+		if _, ok := object[name]; !ok {
+			continue
+		}
+		delete(object, name)
+		if len(object) == 0 {
+			delete(fields, parent)
+			continue
+		}
+		encoded, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+		fields[parent] = encoded
+	}
+	return nil
+}
+
+// managedUnknownRuleFields returns the unknown fields of a rule read from Kibana which are kept in rule_content: the
+// fields of the managedRuleFields allowlist and the configured fields, but none of the read-only fields. Every field
+// but the read-only ones is kept when nothing is configured, such as on import.
+func managedUnknownRuleFields(unknown map[string]json.RawMessage, configured string) (map[string]json.RawMessage, error) {
+	var configuredFields map[string]json.RawMessage
+	if configured != "" {
+		if err := json.Unmarshal([]byte(configured), &configuredFields); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make(map[string]json.RawMessage, len(unknown))
+	for field, value := range unknown {
+		_, ok := configuredFields[field]
+		if ok || configuredFields == nil || slices.Contains(managedRuleFields, field) {
+			fields[field] = value
+		}
+	}
+	if err := removeReadOnlyRuleFields(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// getDetectionRule reads a rule into response and returns the fields of the response which the transfer object does
+// not model, so that the fields added by new Kibana releases are not lost
+func getDetectionRule(ctx context.Context, client *helpers.Client, path string, response *transferobjects.DetectionRuleResponse) (map[string]json.RawMessage, error) {
+	raw, err := client.GetRaw(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw.Bytes(), response); err != nil {
+		return nil, err
+	}
+	return transferobjects.UnknownFields(raw.Bytes(), transferobjects.DetectionRule{})
+}
+
+// withUnknownRuleFields returns the request body of a rule with the fields of its content which the transfer object
+// does not model
+func withUnknownRuleFields(body *transferobjects.DetectionRule, content string) (map[string]json.RawMessage, error) {
+	unknown, err := transferobjects.UnknownFields([]byte(content), transferobjects.DetectionRule{})
+	if err != nil {
+		return nil, err
+	}
+	// The read-only fields of an exported rule would be rejected
+	if err := removeReadOnlyRuleFields(unknown); err != nil {
+		return nil, err
+	}
+	return withUnknownRuleFieldsOf(body, unknown)
+}
+
+// withUnknownRuleFieldsOf returns the JSON fields of a rule together with the unknown fields
+func withUnknownRuleFieldsOf(rule interface{}, unknown map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for field, value := range unknown {
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+// configuredFieldsOnly removes from the content the fields which are not in the configured content
+func configuredFieldsOnly(content string, configured string) (string, error) {
+	var fields, configuredFields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &fields); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal([]byte(configured), &configuredFields); err != nil {
+		return "", err
+	}
+	for field := range fields {
+		if _, ok := configuredFields[field]; !ok {
+			delete(fields, field)
+		}
+	}
+	output, err := json.Marshal(fields)
+	return string(output), err
+}
+
+// changedRuleFields returns the fields of the planned content which differ from the prior content.
+// The fields removed from the configuration are not returned, they are no longer managed.
+func changedRuleFields(prior string, planned string) (map[string]json.RawMessage, error) {
+	var priorFields, plannedFields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(prior), &priorFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(planned), &plannedFields); err != nil {
+		return nil, err
+	}
+	changed := map[string]json.RawMessage{}
+	for field, value := range plannedFields {
+		// Compare the fields within an object, so that the order of set-like arrays such as tags is ignored
+		priorValue, ok := priorFields[field]
+		if !ok || !customtypes.SemanticallyEqual(
+			fmt.Sprintf(`{%q:%s}`, field, priorValue),
+			fmt.Sprintf(`{%q:%s}`, field, value),
+		) {
+			changed[field] = value
+		}
+	}
+	return changed, nil
+}
+
+// ruleIDOf returns the rule_id of a rule content, or an empty string when it has none or is not valid JSON
+func ruleIDOf(content string) string {
+	var rule struct {
+		RuleID string `json:"rule_id"`
+	}
+	if err := helpers.ObjectFromJSON(content, &rule); err != nil {
+		return ""
+	}
+	return rule.RuleID
+}
+
+// requiresReplaceIfRuleIDChanged replaces the rule when its rule_id changes, Kibana rejects the updates changing it.
+// Adding or removing the rule_id does not replace the rule, Kibana generates one when it is missing.
+func requiresReplaceIfRuleIDChanged(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+	if req.PlanValue.IsUnknown() || req.StateValue.IsNull() {
+		return
+	}
+	planRuleID, stateRuleID := ruleIDOf(req.PlanValue.ValueString()), ruleIDOf(req.StateValue.ValueString())
+	resp.RequiresReplace = planRuleID != "" && stateRuleID != "" && planRuleID != stateRuleID
 }
 
 func (r *DetectionRuleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
@@ -201,8 +589,19 @@ func (r *DetectionRuleResource) Update(ctx context.Context, req resource.UpdateR
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Process the content
-	err := helpers.ObjectFromJSON(data.RuleContent.ValueString(), &body)
+	content, diags := data.mergedRuleContent(ctx, "[Update][DetectionRule]")
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.UpdateStrategy.ValueString() == updateStrategyPatch {
+		r.patch(ctx, client, req, resp, data, content)
+		return
+	}
+	err := helpers.ObjectFromJSON(content, &body)
 	if err != nil {
 		resp.Diagnostics.AddError("[Update][DetectionRule] Parser Error", fmt.Sprintf("Unable to parse state file, got error: %s", err))
 		return
@@ -229,12 +628,82 @@ func (r *DetectionRuleResource) Update(ctx context.Context, req resource.UpdateR
 		body.ID = data.Id.ValueString()
 	}
 
+	adaptDetectionRule(body, client.Version())
+	request, err := withUnknownRuleFields(body, content)
+	if err != nil {
+		resp.Diagnostics.AddError("[Update][DetectionRule] Parser Error", fmt.Sprintf("Unable to encode the rule, got error: %s", err))
+		return
+	}
+
 	// Update via API
 	var response transferobjects.DetectionRuleResponse
-	if err := r.client.Put("/detection_engine/rules", body, &response, itemsToRemove); err != nil {
-		resp.Diagnostics.AddError("[Update][DetectionRule] Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	if err := client.Put(ctx, "/detection_engine/rules", request, &response, itemsToRemove); err != nil {
+		addClientError(&resp.Diagnostics, "[Update][DetectionRule]", err)
+		return
+	}
+	data.setComputedAttributes(client, &response)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// patch sends the fields of the rule which changed since the prior state, the other fields are left untouched
+func (r *DetectionRuleResource) patch(ctx context.Context, client *helpers.Client, req resource.UpdateRequest, resp *resource.UpdateResponse, data *DetectionRuleResourceModel, content string) {
+	var state DetectionRuleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	prior, diags := state.mergedRuleContent(ctx, "[Update][DetectionRule]")
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
+	fields, err := changedRuleFields(prior, content)
+	if err != nil {
+		resp.Diagnostics.AddError("[Update][DetectionRule] Parser Error", fmt.Sprintf("Unable to compare the rule with its prior state, got error: %s", err))
+		return
+	}
+	// The rule is identified by its id, a new rule_id forces a replacement
+	delete(fields, "rule_id")
+	if err := removeReadOnlyRuleFields(fields); err != nil {
+		resp.Diagnostics.AddError("[Update][DetectionRule] Parser Error", fmt.Sprintf("Unable to remove the read-only fields, got error: %s", err))
+		return
+	}
+
+	if raw, ok := fields["investigation_fields"]; ok {
+		rule := transferobjects.DetectionRule{InvestigationFields: &transferobjects.InvestigationFields{}}
+		if err := json.Unmarshal(raw, rule.InvestigationFields); err != nil {
+			resp.Diagnostics.AddError("[Update][DetectionRule] Parser Error", fmt.Sprintf("Unable to parse investigation_fields, got error: %s", err))
+			return
+		}
+		adaptDetectionRule(&rule, client.Version())
+		if fields["investigation_fields"], err = json.Marshal(rule.InvestigationFields); err != nil {
+			resp.Diagnostics.AddError("[Update][DetectionRule] Parser Error", fmt.Sprintf("Unable to encode investigation_fields, got error: %s", err))
+			return
+		}
+	}
+
+	if len(fields) > 0 {
+		id, _ := json.Marshal(data.Id.ValueString())
+		fields["id"] = id
+
+		// Update via API
+		var response transferobjects.DetectionRuleResponse
+		if err := client.Patch(ctx, "/detection_engine/rules", fields, &response, nil); err != nil {
+			addClientError(&resp.Diagnostics, "[Update][DetectionRule]", err)
+			return
+		}
+		data.setComputedAttributes(client, &response)
+	} else {
+		// Nothing changed in Kibana
+		data.LastExecutionStatus = state.LastExecutionStatus
+		data.LastExecutionDate = state.LastExecutionDate
+		data.LastExecutionMessage = state.LastExecutionMessage
+		data.Revision = state.Revision
+		data.Version = state.Version
+		data.KibanaURL = state.KibanaURL
+	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -250,21 +719,122 @@ func (r *DetectionRuleResource) Delete(ctx context.Context, req resource.DeleteR
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Get via API
 	path := fmt.Sprintf("/detection_engine/rules?id=%s", data.Id.ValueString())
-	if err := r.client.Delete(path); err != nil {
-		if strings.Contains(err.Error(), "404") {
+	if err := client.Delete(ctx, path); err != nil {
+		if helpers.IsNotFound(err) {
 			resp.Diagnostics.AddWarning("[Delete][DetectionRule] Client Error", fmt.Sprintf("Resource not found. Will destroy if needed. Got error: %s", err))
 			data.Id = types.StringNull()
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError("[Delete][DetectionRule] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+			addClientError(&resp.Diagnostics, "[Delete][DetectionRule]", err)
 			return
 		}
 	}
 }
 
+func (r *DetectionRuleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	modifyPlanSpaceID(ctx, r.client, req, resp)
+
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	// Reject the fields the server does not support before anything is changed
+	var data DetectionRuleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.RuleContent.IsUnknown() {
+		return
+	}
+	// Values which are not known yet are checked during the apply
+	content, diags := data.mergedRuleContent(ctx, "[ModifyPlan][DetectionRule]")
+	if diags.HasError() {
+		return
+	}
+	checkFieldRequirements(&resp.Diagnostics, "[ModifyPlan][DetectionRule]", r.client.Version(), content, detectionRuleRequirements)
+}
+
+func (r *DetectionRuleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data DetectionRuleResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if strategy := data.UpdateStrategy; !strategy.IsNull() && !strategy.IsUnknown() &&
+		strategy.ValueString() != updateStrategyPut && strategy.ValueString() != updateStrategyPatch {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("update_strategy"),
+			"[ValidateConfig][DetectionRule] Invalid Update Strategy",
+			fmt.Sprintf("update_strategy must be '%s' or '%s', got: %s", updateStrategyPut, updateStrategyPatch, strategy.ValueString()),
+		)
+	}
+
+	if data.RuleContent.IsNull() && !data.usesTypedFields() {
+		resp.Diagnostics.AddError(
+			"[ValidateConfig][DetectionRule] Missing Rule Content",
+			"The rule must be described by rule_content, by the typed attributes and blocks (name, type, query, ...) or by both.",
+		)
+		return
+	}
+	data.checkRuleFieldConflicts(&resp.Diagnostics, "[ValidateConfig][DetectionRule]")
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Check the fields required by the rule type, values which are not known yet are checked by Kibana
+	fields, diags := data.configuredRuleFields(ctx, "[ValidateConfig][DetectionRule]")
+	resp.Diagnostics.Append(diags...)
+	if fields != nil {
+		checkRuleFields(&resp.Diagnostics, "[ValidateConfig][DetectionRule]", fields)
+	}
+}
+
+// ImportState imports a rule by "<id>" or "rule_id:<rule_id>", optionally prefixed by "<space_id>/"
 func (r *DetectionRuleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	spaceID := types.StringNull()
+	id := req.ID
+	if space, objectID, found := strings.Cut(req.ID, "/"); found {
+		spaceID = types.StringValue(space)
+		id = objectID
+	}
+	ruleID, found := strings.CutPrefix(id, "rule_id:")
+	if !found {
+		importStateWithSpace(ctx, req, resp)
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("[ImportState][DetectionRule] Unconfigured Provider", "The provider must be configured to import a rule by rule_id.")
+		return
+	}
+	client := spaceClient(r.client, &spaceID)
+
+	// The id differs per cluster and space, the rule_id does not
+	var response transferobjects.DetectionRuleResponse
+	unknown, err := getDetectionRule(ctx, client, "/detection_engine/rules?rule_id="+url.QueryEscape(ruleID), &response)
+	if err != nil {
+		if helpers.IsNotFound(err) {
+			resp.Diagnostics.AddError(
+				"[ImportState][DetectionRule] Rule Not Found",
+				fmt.Sprintf("No rule with the rule_id '%s' exists in the space '%s'.", ruleID, spaceID.ValueString()),
+			)
+			return
+		}
+		addClientError(&resp.Diagnostics, "[ImportState][DetectionRule]", err)
+		return
+	}
+
+	var data DetectionRuleResourceModel
+	content, diags := data.ruleContentFromResponse(&response, unknown, client.Version(), "[ImportState][DetectionRule]")
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), response.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("space_id"), spaceID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("rule_content"), customtypes.NewNormalizedJSONValue(content))...)
 }
@@ -1,16 +1,29 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"terraform-provider-elastic-siem-detection/internal/fakeserver"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/customtypes"
 	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
@@ -23,6 +36,9 @@ func generateTestRule() string {
 	ruleContent.RiskScore = 21
 	ruleContent.Severity = "low"
 	ruleContent.Type = "query"
+	ruleContent.Query = "user.name: hacker"
+	ruleContent.Language = "kuery"
+	ruleContent.Index = []string{"logs-*"}
 
 	str, err := json.Marshal(ruleContent)
 	if err != nil {
@@ -124,3 +140,264 @@ resource "elastic-siem-detection_detection_rule" "%s" {
 }
 `, providerConfig, name, content)
 }
+
+// newTestDetectionRuleResource returns a detection rule resource whose client sends its requests to handler
+func newTestDetectionRuleResource(t *testing.T, handler http.HandlerFunc) *DetectionRuleResource {
+	t.Helper()
+	svr := httptest.NewServer(handler)
+	t.Cleanup(svr.Close)
+
+	client, err := helpers.NewClient(&helpers.NewClientInput{Endpoint: svr.URL, ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DetectionRuleResource{client: client}
+}
+
+func TestDetectionRuleImportStateByRuleID(t *testing.T) {
+	r := newTestDetectionRuleResource(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/s/security/api/detection_engine/rules" || r.URL.Query().Get("rule_id") != "my rule" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"statusCode":404,"message":"rule not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"a1b2c3","rule_id":"my rule","name":"My rule","type":"query","max_signals":100,"created_by":"elastic","version":3}`)
+	})
+	ctx := context.Background()
+
+	resp := fwresource.ImportStateResponse{State: newDetectionRuleState(t)}
+	r.ImportState(ctx, fwresource.ImportStateRequest{ID: "security/rule_id:my rule"}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+	var data DetectionRuleResourceModel
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Id.ValueString() != "a1b2c3" || data.SpaceID.ValueString() != "security" {
+		t.Errorf("Expected the rule id and space to be imported, got %s and %s", data.Id, data.SpaceID)
+	}
+	if expected := `{"name":"My rule","rule_id":"my rule","type":"query"}`; data.RuleContent.ValueString() != expected {
+		t.Errorf("Expected rule_content %s, got %s", expected, data.RuleContent.ValueString())
+	}
+
+	resp = fwresource.ImportStateResponse{State: newDetectionRuleState(t)}
+	r.ImportState(ctx, fwresource.ImportStateRequest{ID: "rule_id:unknown"}, &resp)
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "[ImportState][DetectionRule] Rule Not Found" {
+		t.Errorf("Expected a missing rule error, got: %v", resp.Diagnostics)
+	}
+}
+
+func TestDetectionRuleReadAdoptsRuleID(t *testing.T) {
+	r := newTestDetectionRuleResource(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("rule_id") != "my_rule" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"statusCode":404,"message":"rule not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"recreated","rule_id":"my_rule","name":"My rule"}`)
+	})
+	ctx := context.Background()
+
+	for content, expectedID := range map[string]string{
+		`{"rule_id":"my_rule","name":"My rule"}`:    "recreated",
+		`{"rule_id":"other_rule","name":"My rule"}`: "",
+	} {
+		state := newDetectionRuleStateWith(t, map[string]interface{}{"id": "deleted", "rule_content": content})
+		resp := fwresource.ReadResponse{State: state}
+		r.Read(ctx, fwresource.ReadRequest{State: state}, &resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatal(resp.Diagnostics)
+		}
+
+		var id types.String
+		if !resp.State.Raw.IsNull() {
+			if diags := resp.State.GetAttribute(ctx, path.Root("id"), &id); diags.HasError() {
+				t.Fatal(diags)
+			}
+		}
+		if id.ValueString() != expectedID {
+			t.Errorf("Expected the id %q for %s, got %s", expectedID, content, id)
+		}
+	}
+}
+
+func TestDetectionRuleReadComputedAttributes(t *testing.T) {
+	var meta string
+	r := newTestDetectionRuleResource(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":"a1b2c3","rule_id":"my_rule","name":"My rule","revision":3,"version":2,%s`+
+			`"execution_summary":{"last_execution":{"date":"2024-05-06T07:08:09.123Z","status":"failed","message":"Index not found"}}}`, meta)
+	})
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		meta, kibanaURL string
+	}{
+		{meta: ``, kibanaURL: r.client.URL("/app/security/rules/id/a1b2c3")},
+		{meta: `"meta":{"kibana_siem_app_url":"https://kibana.example.com/s/team/app/security"},`, kibanaURL: "https://kibana.example.com/s/team/app/security/rules/id/a1b2c3"},
+	} {
+		meta = test.meta
+		state := newDetectionRuleStateWith(t, map[string]interface{}{"id": "a1b2c3", "rule_content": `{"rule_id":"my_rule","name":"My rule"}`})
+		resp := fwresource.ReadResponse{State: state}
+		r.Read(ctx, fwresource.ReadRequest{State: state}, &resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatal(resp.Diagnostics)
+		}
+
+		var data DetectionRuleResourceModel
+		if diags := resp.State.Get(ctx, &data); diags.HasError() {
+			t.Fatal(diags)
+		}
+		for name, values := range map[string][2]string{
+			"last_execution_status":  {"failed", data.LastExecutionStatus.ValueString()},
+			"last_execution_date":    {"2024-05-06T07:08:09Z", data.LastExecutionDate.ValueString()},
+			"last_execution_message": {"Index not found", data.LastExecutionMessage.ValueString()},
+			"revision":               {"3", data.Revision.String()},
+			"version":                {"2", data.Version.String()},
+			"kibana_url":             {test.kibanaURL, data.KibanaURL.ValueString()},
+		} {
+			if values[0] != values[1] {
+				t.Errorf("Expected %s to be %q, got %q", name, values[0], values[1])
+			}
+		}
+	}
+}
+
+func TestRequiresReplaceIfRuleIDChanged(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		state, plan string
+		replace     bool
+	}{
+		{state: `{"rule_id":"a","name":"x"}`, plan: `{"rule_id":"a","name":"y"}`, replace: false},
+		{state: `{"rule_id":"a"}`, plan: `{"rule_id":"b"}`, replace: true},
+		{state: `{"rule_id":"a"}`, plan: `{"name":"x"}`, replace: false},
+		{state: `{"name":"x"}`, plan: `{"rule_id":"b"}`, replace: false},
+	} {
+		var resp stringplanmodifier.RequiresReplaceIfFuncResponse
+		requiresReplaceIfRuleIDChanged(ctx, planmodifier.StringRequest{
+			StateValue: types.StringValue(test.state),
+			PlanValue:  types.StringValue(test.plan),
+		}, &resp)
+		if resp.RequiresReplace != test.replace {
+			t.Errorf("Expected replace=%v from %s to %s", test.replace, test.state, test.plan)
+		}
+	}
+}
+
+func TestDetectionRuleUpdatePatch(t *testing.T) {
+	var method, body string
+	r := newTestDetectionRuleResource(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, body = r.Method, string(b)
+		fmt.Fprint(w, `{"id":"a1b2c3","rule_id":"my_rule"}`)
+	})
+	ctx := context.Background()
+
+	newValue := func(content string) tfsdk.State {
+		return newDetectionRuleStateWith(t, map[string]interface{}{
+			"id":              "a1b2c3",
+			"space_id":        "default",
+			"update_strategy": "patch",
+			"rule_content":    content,
+		})
+	}
+	state := newValue(`{"rule_id":"my_rule","name":"Old name","tags":["a","b"],"interval":"5m"}`)
+	plan := newValue(`{"rule_id":"my_rule","name":"New name","tags":["b","a"]}`)
+
+	resp := fwresource.UpdateResponse{State: state}
+	r.Update(ctx, fwresource.UpdateRequest{State: state, Plan: tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw}}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+	if expected := `{"id":"a1b2c3","name":"New name"}`; method != http.MethodPatch || body != expected {
+		t.Errorf("Expected PATCH %s, got %s %s", expected, method, body)
+	}
+
+	// Nothing is sent when only fields are removed
+	method = ""
+	plan = newValue(`{"rule_id":"my_rule","name":"Old name"}`)
+	r.Update(ctx, fwresource.UpdateRequest{State: state, Plan: tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw}}, &resp)
+	if resp.Diagnostics.HasError() || method != "" {
+		t.Errorf("Expected no request, got %s %s (%v)", method, body, resp.Diagnostics)
+	}
+}
+
+func TestConfiguredFieldsOnly(t *testing.T) {
+	content, err := configuredFieldsOnly(`{"name":"rule","actions":[{"id":"soc"}],"exceptions_list":[{"id":"ui"}]}`, `{"name":"other"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != `{"name":"rule"}` {
+		t.Errorf("Expected only the configured fields, got %s", content)
+	}
+}
+
+func TestDetectionRuleESQL(t *testing.T) {
+	objects := make(map[string]map[string]interface{})
+	svr := fakeserver.NewFakeServer(test_port+1, objects, true, false, "")
+	defer svr.Shutdown()
+
+	client, err := helpers.NewClient(&helpers.NewClientInput{Hostname: test_host, Port: test_port + 1, ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &DetectionRuleResource{client: client}
+	ctx := context.Background()
+
+	newState := func(content string) tfsdk.State {
+		return newDetectionRuleStateWith(t, map[string]interface{}{"rule_content": content, "update_strategy": "put"})
+	}
+	validate := func(content string) diag.Diagnostics {
+		state := newState(content)
+		var resp fwresource.ValidateConfigResponse
+		r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Schema: state.Schema, Raw: state.Raw}}, &resp)
+		return resp.Diagnostics
+	}
+
+	// namespace is not modelled by the transfer object, it is passed through
+	content := `{"rule_id":"esql_rule","name":"ES|QL rule","description":"Detects hackers","type":"esql","language":"esql","namespace":"team-a",` +
+		`"query":"FROM logs-* METADATA _id, _index, _version | WHERE user.name == \"hacker\"","risk_score":47,"severity":"medium"}`
+	if diags := validate(content); len(diags) > 0 {
+		t.Fatalf("Unexpected diagnostics: %v", diags)
+	}
+
+	// Create and read back the rule
+	plan := newState(content)
+	createResp := fwresource.CreateResponse{State: newDetectionRuleState(t)}
+	r.Create(ctx, fwresource.CreateRequest{Plan: tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw}}, &createResp)
+	if createResp.Diagnostics.HasError() {
+		t.Fatal(createResp.Diagnostics)
+	}
+	stored := objects["rules"]
+	if stored["type"] != "esql" || stored["language"] != "esql" || stored["index"] != nil || stored["namespace"] != "team-a" {
+		t.Errorf("Unexpected rule sent to Kibana: %v", stored)
+	}
+
+	readResp := fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, &readResp)
+	if readResp.Diagnostics.HasError() {
+		t.Fatal(readResp.Diagnostics)
+	}
+	var data DetectionRuleResourceModel
+	if diags := readResp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !customtypes.SemanticallyEqual(data.RuleContent.ValueString(), content) {
+		t.Errorf("Expected the rule to round-trip, got %s", data.RuleContent.ValueString())
+	}
+
+	// ES|QL constraints
+	diags := validate(`{"type":"esql","language":"esql","query":"FROM logs-* METADATA _id","index":["logs-*"]}`)
+	if diags.ErrorsCount() != 1 || diags.Errors()[0].Summary() != "[ValidateConfig][DetectionRule] Unsupported Rule Field" {
+		t.Errorf("Expected index to be rejected, got: %v", diags)
+	}
+	diags = validate(`{"type":"esql","language":"esql","query":"FROM logs-* | WHERE user.name == \"hacker\""}`)
+	if diags.HasError() || diags.WarningsCount() != 1 || diags.Warnings()[0].Summary() != "[ValidateConfig][DetectionRule] ES|QL Alerts Not Deduplicated" {
+		t.Errorf("Expected a deduplication warning, got: %v", diags)
+	}
+	diags = validate(`{"type":"esql","language":"esql","query":"FROM logs-* | STATS count = COUNT(*) BY user.name"}`)
+	if len(diags) > 0 {
+		t.Errorf("Expected aggregating queries to be accepted, got: %v", diags)
+	}
+}
@@ -0,0 +1,385 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// ruleTypeRequiredFields lists the fields required by each rule type, nested fields are separated by dots and
+// alternative fields by pipes. The language of query rules defaults to kuery, their indices can come from a data view.
+var ruleTypeRequiredFields = map[string][]string{
+	"query":            {"query", "index|data_view_id"},
+	"eql":              {"query"},
+	"threshold":        {"threshold.field", "threshold.value"},
+	"threat_match":     {"threat_index", "threat_query", "threat_mapping"},
+	"machine_learning": {"machine_learning_job_id", "anomaly_threshold"},
+	"new_terms":        {"new_terms_fields", "history_window_start"},
+	"esql":             {"query", "language"},
+}
+
+// ruleTypeUnsupportedFields lists the fields rejected by the rule types, with the reason
+var ruleTypeUnsupportedFields = map[string]map[string]string{
+	"esql": {"index": "the indices are selected by the FROM command of the query"},
+}
+
+// ruleTypeLanguages lists the query languages accepted by the rule types which restrict them
+var ruleTypeLanguages = map[string][]string{
+	"eql":  {"eql"},
+	"esql": {"esql"},
+}
+
+// alertSuppressionValues lists the values accepted by the enumerated alert_suppression fields
+var alertSuppressionValues = map[string][]string{
+	"missing_fields_strategy": {"suppress", "doNotSuppress"},
+	"duration.unit":           {"s", "m", "h"},
+}
+
+// alertSuppressionMaxGroupBy is the number of fields alerts can be grouped by
+const alertSuppressionMaxGroupBy = 3
+
+// endpointResponseCommands lists the Elastic Defend commands of the .endpoint response actions,
+// with whether they act on a process selected by their config
+var endpointResponseCommands = map[string]bool{
+	"isolate":         false,
+	"kill-process":    true,
+	"suspend-process": true,
+}
+
+// esqlStatsCommand matches the ES|QL queries aggregating their results
+var esqlStatsCommand = regexp.MustCompile(`(?i)\|\s*stats\s`)
+
+// esqlMetadataID matches the ES|QL queries requesting the _id metadata field in their FROM command
+var esqlMetadataID = regexp.MustCompile(`(?i)^\s*from\s[^|]*\smetadata\s[^|]*\b_id\b`)
+
+// ruleFieldValues lists the values accepted by the enumerated rule fields
+var ruleFieldValues = map[string][]string{
+	"type":     {"query", "saved_query", "eql", "esql", "threshold", "threat_match", "machine_learning", "new_terms"},
+	"severity": {"low", "medium", "high", "critical"},
+	"language": {"kuery", "lucene", "eql", "esql"},
+}
+
+// ruleFields is the decoded content of a rule, made of rule_content and the typed attributes and blocks
+type ruleFields struct {
+	values map[string]interface{}
+	// typed holds the fields which have a typed attribute or block, and whether it is set
+	typed map[string]bool
+	// content is set when rule_content is configured
+	content bool
+}
+
+// lookup returns the value of a field, nested fields are separated by dots
+func (f ruleFields) lookup(field string) (interface{}, bool) {
+	var value interface{} = f.values
+	for _, name := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok || value == nil {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// path returns the attribute path to report the errors of a field on: its typed attribute or block when it is set,
+// or when the rule has no rule_content, and rule_content otherwise
+func (f ruleFields) path(field string) path.Path {
+	names := strings.Split(field, ".")
+	set, hasAttribute := f.typed[names[0]]
+	if !set && (!hasAttribute || f.content) {
+		return path.Root("rule_content")
+	}
+	p := path.Root(names[0])
+	for _, name := range names[1:] {
+		p = p.AtName(name)
+	}
+	return p
+}
+
+// isFullyKnown reports whether a value and all the values it holds are known
+func isFullyKnown(ctx context.Context, value attr.Value) bool {
+	tfValue, err := value.ToTerraformValue(ctx)
+	return err == nil && tfValue.IsFullyKnown()
+}
+
+// configuredRuleFields returns the fields of the configured rule, or nil when some of them are not known yet
+func (m *DetectionRuleResourceModel) configuredRuleFields(ctx context.Context, prefix string) (*ruleFields, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if m.RuleContent.IsUnknown() {
+		return nil, diags
+	}
+	fields := &ruleFields{values: map[string]interface{}{}, typed: map[string]bool{}, content: !m.RuleContent.IsNull()}
+	for field, value := range m.typedFields() {
+		if isSet(field, value) && !isFullyKnown(ctx, value) {
+			return nil, diags
+		}
+		fields.typed[field] = isSet(field, value)
+	}
+
+	if !m.RuleContent.IsNull() {
+		if err := json.Unmarshal([]byte(m.RuleContent.ValueString()), &fields.values); err != nil {
+			// Invalid JSON is reported by the attribute type
+			return nil, diags
+		}
+	}
+	for field, value := range m.typedValues(ctx, &diags) {
+		// Decode the typed values as rule_content is, numbers become float64
+		raw, err := json.Marshal(value)
+		if err != nil {
+			diags.AddAttributeError(path.Root(field), prefix+" Parser Error", fmt.Sprintf("Unable to encode %s, got error: %s", field, err))
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			diags.AddAttributeError(path.Root(field), prefix+" Parser Error", fmt.Sprintf("Unable to decode %s, got error: %s", field, err))
+			continue
+		}
+		fields.values[field] = decoded
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+	return fields, diags
+}
+
+// checkRuleFields adds an error for every required field missing for the rule type and every invalid value
+func checkRuleFields(diags *diag.Diagnostics, prefix string, fields *ruleFields) {
+	for _, field := range []string{"type", "severity", "language"} {
+		value, ok := fields.lookup(field)
+		if !ok {
+			continue
+		}
+		if s, isString := value.(string); !isString || !slices.Contains(ruleFieldValues[field], s) {
+			diags.AddAttributeError(
+				fields.path(field),
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The field '%s' must be one of %s, got: %v", field, strings.Join(ruleFieldValues[field], ", "), value),
+			)
+		}
+	}
+
+	if value, ok := fields.lookup("risk_score"); ok {
+		if score, isNumber := value.(float64); !isNumber || score < 0 || score > 100 {
+			diags.AddAttributeError(
+				fields.path("risk_score"),
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The field 'risk_score' must be a number from 0 to 100, got: %v", value),
+			)
+		}
+	}
+
+	ruleType, _ := fields.lookup("type")
+	typeName, _ := ruleType.(string)
+	for _, required := range ruleTypeRequiredFields[typeName] {
+		alternatives := strings.Split(required, "|")
+		if !slices.ContainsFunc(alternatives, func(field string) bool {
+			_, ok := fields.lookup(field)
+			return ok
+		}) {
+			diags.AddAttributeError(
+				fields.path(alternatives[0]),
+				prefix+" Missing Rule Field",
+				fmt.Sprintf("The field '%s' is required by the rules of type '%s'.", strings.Join(alternatives, "' or '"), typeName),
+			)
+		}
+	}
+	for field, reason := range ruleTypeUnsupportedFields[typeName] {
+		if _, ok := fields.lookup(field); ok {
+			diags.AddAttributeError(
+				fields.path(field),
+				prefix+" Unsupported Rule Field",
+				fmt.Sprintf("The field '%s' is not supported by the rules of type '%s', %s.", field, typeName, reason),
+			)
+		}
+	}
+	if languages, ok := ruleTypeLanguages[typeName]; ok {
+		if language, ok := fields.lookup("language"); ok && !slices.Contains(languages, fmt.Sprint(language)) {
+			diags.AddAttributeError(
+				fields.path("language"),
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The rules of type '%s' must use the language %s, got: %v", typeName, strings.Join(languages, ", "), language),
+			)
+		}
+	}
+
+	if typeName == "esql" {
+		checkESQLQuery(diags, prefix, fields)
+	}
+	checkAlertSuppression(diags, prefix, fields, typeName)
+	checkResponseActions(diags, prefix, fields)
+}
+
+// checkAlertSuppression adds an error for every alert_suppression field which is missing, invalid or not supported by
+// the rule type. Threshold rules group the alerts by their threshold fields, they only support a duration.
+func checkAlertSuppression(diags *diag.Diagnostics, prefix string, fields *ruleFields, typeName string) {
+	value, ok := fields.lookup("alert_suppression")
+	if !ok {
+		return
+	}
+	if _, isObject := value.(map[string]interface{}); !isObject {
+		diags.AddAttributeError(fields.path("alert_suppression"), prefix+" Invalid Rule Field", fmt.Sprintf("The field 'alert_suppression' must be an object, got: %v", value))
+		return
+	}
+
+	required := []string{"alert_suppression.group_by"}
+	if typeName == "threshold" {
+		required = []string{"alert_suppression.duration"}
+		for _, field := range []string{"alert_suppression.group_by", "alert_suppression.missing_fields_strategy"} {
+			if _, ok := fields.lookup(field); ok {
+				diags.AddAttributeError(
+					fields.path(field),
+					prefix+" Unsupported Rule Field",
+					fmt.Sprintf("The field '%s' is not supported by the rules of type 'threshold', their alerts are grouped by the threshold fields.", field),
+				)
+			}
+		}
+	}
+	for _, field := range required {
+		if _, ok := fields.lookup(field); !ok {
+			diags.AddAttributeError(
+				fields.path(field),
+				prefix+" Missing Rule Field",
+				fmt.Sprintf("The field '%s' is required by the alert suppression of the rules of type '%s'.", field, typeName),
+			)
+		}
+	}
+
+	if groupBy, ok := fields.lookup("alert_suppression.group_by"); ok && typeName != "threshold" {
+		if names, isArray := groupBy.([]interface{}); !isArray || len(names) == 0 || len(names) > alertSuppressionMaxGroupBy {
+			diags.AddAttributeError(
+				fields.path("alert_suppression"),
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The field 'alert_suppression.group_by' must list from 1 to %d fields, got: %v", alertSuppressionMaxGroupBy, groupBy),
+			)
+		}
+	}
+	if _, ok := fields.lookup("alert_suppression.duration"); ok {
+		for _, field := range []string{"alert_suppression.duration.value", "alert_suppression.duration.unit"} {
+			if _, ok := fields.lookup(field); !ok {
+				diags.AddAttributeError(fields.path(field), prefix+" Missing Rule Field", fmt.Sprintf("The field '%s' is required by the suppression duration.", field))
+			}
+		}
+	}
+	if duration, ok := fields.lookup("alert_suppression.duration.value"); ok {
+		if value, isNumber := duration.(float64); !isNumber || value <= 0 || value != float64(int(value)) {
+			diags.AddAttributeError(
+				fields.path("alert_suppression"),
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The field 'alert_suppression.duration.value' must be a positive integer, got: %v", duration),
+			)
+		}
+	}
+	for field, values := range alertSuppressionValues {
+		value, ok := fields.lookup("alert_suppression." + field)
+		if s, isString := value.(string); ok && (!isString || !slices.Contains(values, s)) {
+			diags.AddAttributeError(
+				fields.path("alert_suppression"),
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The field 'alert_suppression.%s' must be one of %s, got: %v", field, strings.Join(values, ", "), value),
+			)
+		}
+	}
+}
+
+// checkESQLQuery warns when the alerts of an ES|QL rule cannot be deduplicated. Kibana identifies the alerts of
+// the queries which do not aggregate their results by the _id of the source documents, it must be requested.
+func checkESQLQuery(diags *diag.Diagnostics, prefix string, fields *ruleFields) {
+	value, _ := fields.lookup("query")
+	query, ok := value.(string)
+	if !ok || esqlStatsCommand.MatchString(query) || esqlMetadataID.MatchString(query) {
+		return
+	}
+	diags.AddAttributeWarning(
+		fields.path("query"),
+		prefix+" ES|QL Alerts Not Deduplicated",
+		"The query neither aggregates its results nor requests the _id metadata field, so the same events can create alerts on every rule run. "+
+			"Add `METADATA _id, _index, _version` to the FROM command of the query.",
+	)
+}
+
+// checkResponseActions adds an error for every response action which is not an Osquery (.osquery)
+// or Elastic Defend (.endpoint) action, or whose params the action does not accept
+func checkResponseActions(diags *diag.Diagnostics, prefix string, fields *ruleFields) {
+	value, ok := fields.lookup("response_actions")
+	if !ok {
+		return
+	}
+	p := fields.path("response_actions")
+	actions, isArray := value.([]interface{})
+	if !isArray {
+		diags.AddAttributeError(p, prefix+" Invalid Rule Field", fmt.Sprintf("The field 'response_actions' must be an array, got: %v", value))
+		return
+	}
+	for i, item := range actions {
+		name := fmt.Sprintf("response_actions[%d]", i)
+		action, _ := item.(map[string]interface{})
+		params, _ := action["params"].(map[string]interface{})
+		if action == nil || params == nil {
+			diags.AddAttributeError(p, prefix+" Invalid Rule Field", fmt.Sprintf("The field '%s' must be an object with action_type_id and params, got: %v", name, item))
+			continue
+		}
+		switch action["action_type_id"] {
+		case ".osquery":
+			_, hasQuery := params["query"]
+			_, hasSavedQuery := params["saved_query_id"]
+			_, hasPack := params["pack_id"]
+			if !hasQuery && !hasSavedQuery && !hasPack {
+				diags.AddAttributeError(p, prefix+" Missing Rule Field", fmt.Sprintf("The Osquery action '%s' needs a query, saved_query_id or pack_id param.", name))
+			} else if hasPack && (hasQuery || hasSavedQuery) {
+				diags.AddAttributeError(p, prefix+" Invalid Rule Field", fmt.Sprintf("The Osquery action '%s' runs either a pack_id or a query, not both.", name))
+			}
+		case ".endpoint":
+			command, _ := params["command"].(string)
+			needsConfig, known := endpointResponseCommands[command]
+			if !known {
+				commands := make([]string, 0, len(endpointResponseCommands))
+				for c := range endpointResponseCommands {
+					commands = append(commands, c)
+				}
+				slices.Sort(commands)
+				diags.AddAttributeError(
+					p,
+					prefix+" Invalid Rule Field",
+					fmt.Sprintf("The command of the Elastic Defend action '%s' must be one of %s, got: %v", name, strings.Join(commands, ", "), params["command"]),
+				)
+				continue
+			}
+			if !needsConfig {
+				continue
+			}
+			// With overwrite, the process.pid of the alert is used and the field is left empty
+			verb := strings.TrimSuffix(command, "-process")
+			config, isObject := params["config"].(map[string]interface{})
+			field, _ := config["field"].(string)
+			overwrite, _ := config["overwrite"].(bool)
+			if !isObject {
+				diags.AddAttributeError(
+					p,
+					prefix+" Missing Rule Field",
+					fmt.Sprintf("The Elastic Defend action '%s' needs the config param selecting the process to %s.", name, verb),
+				)
+			} else if !overwrite && field == "" {
+				diags.AddAttributeError(
+					p,
+					prefix+" Missing Rule Field",
+					fmt.Sprintf("The Elastic Defend action '%s' needs the config.field param holding the process to %s, unless config.overwrite is true.", name, verb),
+				)
+			}
+		default:
+			diags.AddAttributeError(
+				p,
+				prefix+" Invalid Rule Field",
+				fmt.Sprintf("The action_type_id of '%s' must be .osquery or .endpoint, got: %v", name, action["action_type_id"]),
+			)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDetectionRuleValidateConfig(t *testing.T) {
+	ctx := context.Background()
+	for name, test := range map[string]struct {
+		config map[string]interface{}
+		errors map[string]path.Path
+	}{
+		"valid query rule": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"query","query":"user.name: hacker","language":"kuery","index":["logs-*"],"severity":"low","risk_score":21}`,
+			},
+		},
+		"valid typed rule": {
+			config: map[string]interface{}{
+				"type":     "eql",
+				"query":    "process where true",
+				"language": "eql",
+			},
+		},
+		"acceptance test rule": {
+			config: map[string]interface{}{
+				"rule_content": generateTestRule(),
+			},
+		},
+		"valid data view query rule": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"query","query":"user.name: hacker","data_view_id":"security-solution-default","severity":"low","risk_score":21}`,
+			},
+		},
+		"missing query fields": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"query","language":"kuery"}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'query' is required by the rules of type 'query'.":                   path.Root("rule_content"),
+				"The field 'index' or 'data_view_id' is required by the rules of type 'query'.": path.Root("rule_content"),
+			},
+		},
+		"missing typed fields": {
+			config: map[string]interface{}{
+				"type":         "new_terms",
+				"rule_content": `{"new_terms_fields":["host.name"]}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'history_window_start' is required by the rules of type 'new_terms'.": path.Root("rule_content"),
+			},
+		},
+		"missing threshold value": {
+			config: map[string]interface{}{
+				"type": "threshold",
+				"threshold": detectionRuleThresholdModel{
+					Field: types.ListValueMust(types.StringType, []attr.Value{types.StringValue("host.name")}),
+					Value: types.Int64Null(),
+				},
+			},
+			errors: map[string]path.Path{
+				"The field 'threshold.value' is required by the rules of type 'threshold'.": path.Root("threshold").AtName("value"),
+			},
+		},
+		"missing threat_match fields": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"threat_match","threat_query":"*:*"}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'threat_index' is required by the rules of type 'threat_match'.":   path.Root("rule_content"),
+				"The field 'threat_mapping' is required by the rules of type 'threat_match'.": path.Root("rule_content"),
+			},
+		},
+		"missing machine_learning fields": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"machine_learning","anomaly_threshold":50}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'machine_learning_job_id' is required by the rules of type 'machine_learning'.": path.Root("rule_content"),
+			},
+		},
+		"missing typed query": {
+			config: map[string]interface{}{
+				"type": "eql",
+			},
+			errors: map[string]path.Path{
+				"The field 'query' is required by the rules of type 'eql'.": path.Root("query"),
+			},
+		},
+		"invalid values": {
+			config: map[string]interface{}{
+				"type":         "eql",
+				"query":        "process where true",
+				"severity":     "urgent",
+				"risk_score":   int64(101),
+				"rule_content": `{"language":"kuery"}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'severity' must be one of low, medium, high, critical, got: urgent": path.Root("severity"),
+				"The field 'risk_score' must be a number from 0 to 100, got: 101":              path.Root("risk_score"),
+				"The rules of type 'eql' must use the language eql, got: kuery":                path.Root("rule_content"),
+			},
+		},
+		"valid alert suppression": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"new_terms","new_terms_fields":["host.name"],"history_window_start":"now-7d",` +
+					`"alert_suppression":{"group_by":["host.name"],"duration":{"value":5,"unit":"m"},"missing_fields_strategy":"doNotSuppress"}}`,
+			},
+		},
+		"threshold alert suppression": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"threshold","threshold":{"field":["host.name"],"value":10},"alert_suppression":{"group_by":["host.name"]}}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'alert_suppression.group_by' is not supported by the rules of type 'threshold', their alerts are grouped by the threshold fields.": path.Root("rule_content"),
+				"The field 'alert_suppression.duration' is required by the alert suppression of the rules of type 'threshold'.":                               path.Root("rule_content"),
+			},
+		},
+		"invalid alert suppression": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"eql","query":"any where true","alert_suppression":{"group_by":["a","b","c","d"],"duration":{"value":1.5,"unit":"d"},"missing_fields_strategy":"ignore"}}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'alert_suppression.group_by' must list from 1 to 3 fields, got: [a b c d]":                       path.Root("rule_content"),
+				"The field 'alert_suppression.duration.value' must be a positive integer, got: 1.5":                         path.Root("rule_content"),
+				"The field 'alert_suppression.duration.unit' must be one of s, m, h, got: d":                                path.Root("rule_content"),
+				"The field 'alert_suppression.missing_fields_strategy' must be one of suppress, doNotSuppress, got: ignore": path.Root("rule_content"),
+			},
+		},
+		"valid response actions": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"eql","query":"process where true","response_actions":[` +
+					`{"action_type_id":".osquery","params":{"query":"select * from processes","timeout":120}},` +
+					`{"action_type_id":".endpoint","params":{"command":"isolate","comment":"Isolated by the rule"}},` +
+					`{"action_type_id":".endpoint","params":{"command":"kill-process","config":{"field":"process.entity_id","overwrite":false}}},` +
+					`{"action_type_id":".endpoint","params":{"command":"suspend-process","config":{"field":"","overwrite":true}}}]}`,
+			},
+		},
+		"invalid response actions": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"eql","query":"process where true","response_actions":[` +
+					`{"action_type_id":".webhook","params":{}},` +
+					`{"action_type_id":".osquery","params":{"timeout":120}},` +
+					`{"action_type_id":".osquery","params":{"pack_id":"pack","query":"select 1"}},` +
+					`{"action_type_id":".endpoint","params":{"command":"shutdown"}},` +
+					`{"action_type_id":".endpoint","params":{"command":"suspend-process"}},` +
+					`{"action_type_id":".endpoint","params":{"command":"kill-process","config":{"field":"","overwrite":false}}}]}`,
+			},
+			errors: map[string]path.Path{
+				"The action_type_id of 'response_actions[0]' must be .osquery or .endpoint, got: .webhook":                                                   path.Root("rule_content"),
+				"The Osquery action 'response_actions[1]' needs a query, saved_query_id or pack_id param.":                                                   path.Root("rule_content"),
+				"The Osquery action 'response_actions[2]' runs either a pack_id or a query, not both.":                                                       path.Root("rule_content"),
+				"The command of the Elastic Defend action 'response_actions[3]' must be one of isolate, kill-process, suspend-process, got: shutdown":        path.Root("rule_content"),
+				"The Elastic Defend action 'response_actions[4]' needs the config param selecting the process to suspend.":                                   path.Root("rule_content"),
+				"The Elastic Defend action 'response_actions[5]' needs the config.field param holding the process to kill, unless config.overwrite is true.": path.Root("rule_content"),
+			},
+		},
+		"invalid type": {
+			config: map[string]interface{}{
+				"rule_content": `{"type":"sigma"}`,
+			},
+			errors: map[string]path.Path{
+				"The field 'type' must be one of query, saved_query, eql, esql, threshold, threat_match, machine_learning, new_terms, got: sigma": path.Root("rule_content"),
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			state := newDetectionRuleStateWith(t, test.config)
+
+			var resp resource.ValidateConfigResponse
+			(&DetectionRuleResource{}).ValidateConfig(ctx, resource.ValidateConfigRequest{
+				Config: tfsdk.Config{Schema: state.Schema, Raw: state.Raw},
+			}, &resp)
+
+			if resp.Diagnostics.ErrorsCount() != len(test.errors) {
+				t.Fatalf("Expected %d errors, got: %v", len(test.errors), resp.Diagnostics)
+			}
+			for _, d := range resp.Diagnostics.Errors() {
+				expected, ok := test.errors[d.Detail()]
+				if !ok {
+					t.Errorf("Unexpected error: %s", d.Detail())
+					continue
+				}
+				if withPath, ok := d.(interface{ Path() path.Path }); !ok || !withPath.Path().Equal(expected) {
+					t.Errorf("Expected the error '%s' on %s", d.Detail(), expected)
+				}
+			}
+		})
+	}
+}
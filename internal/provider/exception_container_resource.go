@@ -3,11 +3,10 @@ package provider
 import (
 	"context"
 	"fmt"
-	"strings"
 	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/customtypes"
 	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
 
-	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
@@ -18,6 +17,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &ExceptionContainerResource{}
 var _ resource.ResourceWithImportState = &ExceptionContainerResource{}
+var _ resource.ResourceWithModifyPlan = &ExceptionContainerResource{}
 
 func NewExceptionContainerResource() resource.Resource {
 	return &ExceptionContainerResource{}
@@ -30,8 +30,9 @@ type ExceptionContainerResource struct {
 
 // ExceptionContainerResourceModel describes the resource data model.
 type ExceptionContainerResourceModel struct {
-	RuleContent types.String `tfsdk:"exception_container_content"`
-	Id          types.String `tfsdk:"id"`
+	RuleContent customtypes.NormalizedJSON `tfsdk:"exception_container_content"`
+	Id          types.String               `tfsdk:"id"`
+	SpaceID     types.String               `tfsdk:"space_id"`
 }
 
 func (r *ExceptionContainerResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -45,7 +46,8 @@ func (r *ExceptionContainerResource) Schema(ctx context.Context, req resource.Sc
 
 		Attributes: map[string]schema.Attribute{
 			"exception_container_content": schema.StringAttribute{
-				MarkdownDescription: "The content of the exception container (JSON encoded string)",
+				MarkdownDescription: "The content of the exception container (JSON encoded string). Formatting, key order and the order of set-like arrays such as `tags` are ignored when comparing it",
+				CustomType:          customtypes.NormalizedJSONType{},
 				Required:            true,
 			},
 			"id": schema.StringAttribute{
@@ -55,6 +57,7 @@ func (r *ExceptionContainerResource) Schema(ctx context.Context, req resource.Sc
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"space_id": spaceIDAttribute("exception container"),
 		},
 	}
 }
@@ -91,6 +94,8 @@ func (r *ExceptionContainerResource) Create(ctx context.Context, req resource.Cr
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Process the content
 	err := helpers.ObjectFromJSON(data.RuleContent.ValueString(), &body)
 	if err != nil {
@@ -100,8 +105,8 @@ func (r *ExceptionContainerResource) Create(ctx context.Context, req resource.Cr
 
 	// Create via API
 	var response transferobjects.ExceptionContainerResponse
-	if err := r.client.Post("/exception_lists", body, &response, itemsToRemove); err != nil {
-		resp.Diagnostics.AddError("[Create][ExceptionContainer] Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	if err := client.Post(ctx, "/exception_lists", body, &response, itemsToRemove); err != nil {
+		addClientError(&resp.Diagnostics, "[Create][ExceptionContainer]", err)
 		return
 	}
 
@@ -122,18 +127,20 @@ func (r *ExceptionContainerResource) Read(ctx context.Context, req resource.Read
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Get via API
 	var response transferobjects.ExceptionContainerResponse
 	path := fmt.Sprintf("/exception_lists?id=%s", data.Id.ValueString())
 
-	if err := r.client.Get(path, &response); err != nil {
-		if strings.Contains(err.Error(), "404") {
+	if err := client.Get(ctx, path, &response); err != nil {
+		if helpers.IsNotFound(err) {
 			resp.Diagnostics.AddWarning("[Read][ExceptionContainer] Client Error", fmt.Sprintf("Resource not found. Will try to recreate if needed. Got error: %s", err))
 			data.Id = types.StringNull()
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError("[Read][ExceptionContainer] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+			addClientError(&resp.Diagnostics, "[Read][ExceptionContainer]", err)
 			return
 		}
 	}
@@ -153,7 +160,7 @@ func (r *ExceptionContainerResource) Read(ctx context.Context, req resource.Read
 		return
 	}
 
-	data.RuleContent = types.StringValue(jsonStr)
+	data.RuleContent = customtypes.NewNormalizedJSONValue(jsonStr)
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -171,6 +178,8 @@ func (r *ExceptionContainerResource) Update(ctx context.Context, req resource.Up
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Process the content
 	err := helpers.ObjectFromJSON(data.RuleContent.ValueString(), &body)
 	if err != nil {
@@ -182,8 +191,8 @@ func (r *ExceptionContainerResource) Update(ctx context.Context, req resource.Up
 
 	// Update via API
 	var response transferobjects.ExceptionContainerResponse
-	if err := r.client.Put("/exception_lists", body, &response, itemsToRemove); err != nil {
-		resp.Diagnostics.AddError("[Update][ExceptionContainer] Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	if err := client.Put(ctx, "/exception_lists", body, &response, itemsToRemove); err != nil {
+		addClientError(&resp.Diagnostics, "[Update][ExceptionContainer]", err)
 		return
 	}
 
@@ -201,21 +210,27 @@ func (r *ExceptionContainerResource) Delete(ctx context.Context, req resource.De
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Get via API
 	apiPath := fmt.Sprintf("/exception_lists?id=%s", data.Id.ValueString())
-	if err := r.client.Delete(apiPath); err != nil {
-		if strings.Contains(err.Error(), "404") {
+	if err := client.Delete(ctx, apiPath); err != nil {
+		if helpers.IsNotFound(err) {
 			resp.Diagnostics.AddWarning("[Delete][ExceptionContainer] Client Error", fmt.Sprintf("Resource not found. Will destroy if needed. Got error: %s", err))
 			data.Id = types.StringNull()
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError("[Delete][ExceptionContainer] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+			addClientError(&resp.Diagnostics, "[Delete][ExceptionContainer]", err)
 			return
 		}
 	}
 }
 
+func (r *ExceptionContainerResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	modifyPlanSpaceID(ctx, r.client, req, resp)
+}
+
 func (r *ExceptionContainerResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	importStateWithSpace(ctx, req, resp)
 }
@@ -3,8 +3,8 @@ package provider
 import (
 	"context"
 	"fmt"
-	"strings"
 	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/customtypes"
 	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
 
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -18,6 +18,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &ExceptionItemResource{}
 var _ resource.ResourceWithImportState = &ExceptionItemResource{}
+var _ resource.ResourceWithModifyPlan = &ExceptionItemResource{}
 
 func NewExceptionItemResource() resource.Resource {
 	return &ExceptionItemResource{}
@@ -30,8 +31,9 @@ type ExceptionItemResource struct {
 
 // ExceptionItemResourceModel describes the resource data model.
 type ExceptionItemResourceModel struct {
-	RuleContent types.String `tfsdk:"exception_item_content"`
-	Id          types.String `tfsdk:"id"`
+	RuleContent customtypes.NormalizedJSON `tfsdk:"exception_item_content"`
+	Id          types.String               `tfsdk:"id"`
+	SpaceID     types.String               `tfsdk:"space_id"`
 }
 
 func (r *ExceptionItemResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -45,7 +47,8 @@ func (r *ExceptionItemResource) Schema(ctx context.Context, req resource.SchemaR
 
 		Attributes: map[string]schema.Attribute{
 			"exception_item_content": schema.StringAttribute{
-				MarkdownDescription: "The content of the exception item (JSON encoded string)",
+				MarkdownDescription: "The content of the exception item (JSON encoded string). Formatting, key order and the order of set-like arrays such as `tags` are ignored when comparing it",
+				CustomType:          customtypes.NormalizedJSONType{},
 				Required:            true,
 			},
 			"id": schema.StringAttribute{
@@ -55,6 +58,7 @@ func (r *ExceptionItemResource) Schema(ctx context.Context, req resource.SchemaR
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"space_id": spaceIDAttribute("exception item"),
 		},
 	}
 }
@@ -91,6 +95,8 @@ func (r *ExceptionItemResource) Create(ctx context.Context, req resource.CreateR
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Process the content
 	err := helpers.ObjectFromJSON(data.RuleContent.ValueString(), &body)
 	if err != nil {
@@ -106,8 +112,8 @@ func (r *ExceptionItemResource) Create(ctx context.Context, req resource.CreateR
 
 	// Create via API
 	var response transferobjects.ExceptionItemResponse
-	if err := r.client.Post("/exception_lists/items", body, &response, itemsToRemove); err != nil {
-		resp.Diagnostics.AddError("[Create][ExceptionItem] Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	if err := client.Post(ctx, "/exception_lists/items", body, &response, itemsToRemove); err != nil {
+		addClientError(&resp.Diagnostics, "[Create][ExceptionItem]", err)
 		return
 	}
 
@@ -128,18 +134,20 @@ func (r *ExceptionItemResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Get via API
 	var response transferobjects.ExceptionItemResponse
 	path := fmt.Sprintf("/exception_lists/items?id=%s", data.Id.ValueString())
 
-	if err := r.client.Get(path, &response); err != nil {
-		if strings.Contains(err.Error(), "404") {
+	if err := client.Get(ctx, path, &response); err != nil {
+		if helpers.IsNotFound(err) {
 			resp.Diagnostics.AddWarning("[Read][ExceptionItem] Client Error", fmt.Sprintf("Resource not found. Will try to recreate if needed. Got error: %s", err))
 			data.Id = types.StringNull()
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError("[Read][ExceptionItem] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+			addClientError(&resp.Diagnostics, "[Read][ExceptionItem]", err)
 			return
 		}
 	}
@@ -164,7 +172,7 @@ func (r *ExceptionItemResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
-	data.RuleContent = types.StringValue(jsonStr)
+	data.RuleContent = customtypes.NewNormalizedJSONValue(jsonStr)
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -182,6 +190,8 @@ func (r *ExceptionItemResource) Update(ctx context.Context, req resource.UpdateR
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Process the content
 	err := helpers.ObjectFromJSON(data.RuleContent.ValueString(), &body)
 	if err != nil {
@@ -200,8 +210,8 @@ func (r *ExceptionItemResource) Update(ctx context.Context, req resource.UpdateR
 
 	// Update via API
 	var response transferobjects.ExceptionItemResponse
-	if err := r.client.Put("/exception_lists/items", body, &response, itemsToRemove); err != nil {
-		resp.Diagnostics.AddError("[Update][ExceptionItem] Client Error", fmt.Sprintf("Error during request, got error: \n%s", err))
+	if err := client.Put(ctx, "/exception_lists/items", body, &response, itemsToRemove); err != nil {
+		addClientError(&resp.Diagnostics, "[Update][ExceptionItem]", err)
 		return
 	}
 
@@ -219,21 +229,39 @@ func (r *ExceptionItemResource) Delete(ctx context.Context, req resource.DeleteR
 		return
 	}
 
+	client := spaceClient(r.client, &data.SpaceID)
+
 	// Get via API
 	path := fmt.Sprintf("/exception_lists/items?id=%s", data.Id.ValueString())
-	if err := r.client.Delete(path); err != nil {
-		if strings.Contains(err.Error(), "404") {
+	if err := client.Delete(ctx, path); err != nil {
+		if helpers.IsNotFound(err) {
 			resp.Diagnostics.AddWarning("[Delete][ExceptionItem] Client Error", fmt.Sprintf("Resource not found. Will destroy if needed. Got error: %s", err))
 			data.Id = types.StringNull()
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError("[Delete][ExceptionItem] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+			addClientError(&resp.Diagnostics, "[Delete][ExceptionItem]", err)
 			return
 		}
 	}
 }
 
+func (r *ExceptionItemResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	modifyPlanSpaceID(ctx, r.client, req, resp)
+
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	// Reject the fields the server does not support before anything is changed
+	var content customtypes.NormalizedJSON
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("exception_item_content"), &content)...)
+	if resp.Diagnostics.HasError() || content.IsNull() || content.IsUnknown() {
+		return
+	}
+	checkFieldRequirements(&resp.Diagnostics, "[ModifyPlan][ExceptionItem]", r.client.Version(), content.ValueString(), exceptionItemRequirements)
+}
+
 func (r *ExceptionItemResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	importStateWithSpace(ctx, req, resp)
 }
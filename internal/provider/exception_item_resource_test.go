@@ -1,14 +1,22 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"terraform-provider-elastic-siem-detection/internal/fakeserver"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
 	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
@@ -112,3 +120,41 @@ resource "elastic-siem-detection_exception_item" "%s" {
 }
 `, providerConfig, name, content)
 }
+
+func TestExceptionItemModifyPlan(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version":{"number":"8.6.0"}}`)
+	}))
+	defer svr.Close()
+
+	client, err := helpers.NewClient(&helpers.NewClientInput{Endpoint: svr.URL, ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := client.DetectVersion(ctx); err != nil {
+		t.Fatal(err)
+	}
+	r := &ExceptionItemResource{client: client}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for content, errors := range map[string]int{
+		`{"item_id":"my_item","name":"My item"}`:                                      0,
+		`{"item_id":"my_item","name":"My item","expire_time":"2030-01-01T00:00:00Z"}`: 1,
+	} {
+		plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+		if diags := plan.SetAttribute(ctx, path.Root("exception_item_content"), content); diags.HasError() {
+			t.Fatal(diags)
+		}
+		config := tfsdk.Config{Schema: plan.Schema, Raw: plan.Raw}
+		state := tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+
+		resp := fwresource.ModifyPlanResponse{Plan: plan}
+		r.ModifyPlan(ctx, fwresource.ModifyPlanRequest{Config: config, Plan: plan, State: state}, &resp)
+		if resp.Diagnostics.ErrorsCount() != errors {
+			t.Errorf("Expected %d errors for %s, got: %v", errors, content, resp.Diagnostics)
+		}
+	}
+}
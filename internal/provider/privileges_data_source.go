@@ -79,8 +79,8 @@ func (d *PrivilegesDataSource) Read(ctx context.Context, req datasource.ReadRequ
 
 	// Get the privileges through the API
 	var response transferobjects.PrivilegesResponse
-	if err := d.client.Get("/detection_engine/privileges", &response); err != nil {
-		resp.Diagnostics.AddError("[Read][Privileges] Client Error", fmt.Sprintf("Error during request, got error: %s", err))
+	if err := d.client.Get(ctx, "/detection_engine/privileges", &response); err != nil {
+		addClientError(&resp.Diagnostics, "[Read][Privileges]", err)
 		return
 	}
 
@@ -2,13 +2,18 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 	"terraform-provider-elastic-siem-detection/internal/helpers"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure ElasticSiemProvider satisfies various provider interfaces.
@@ -24,11 +29,36 @@ type ElasticSiemProvider struct {
 
 // ScaffoldingProviderModel describes the provider data model.
 type ScaffoldingProviderModel struct {
-	Hostname types.String `tfsdk:"hostname"`
-	UseTLS   types.Bool   `tfsdk:"tls"`
-	Port     types.Int64  `tfsdk:"port"`
-	Username types.String `tfsdk:"user"`
-	Password types.String `tfsdk:"password"`
+	Hostname     types.String `tfsdk:"hostname"`
+	UseTLS       types.Bool   `tfsdk:"tls"`
+	Port         types.Int64  `tfsdk:"port"`
+	Username     types.String `tfsdk:"user"`
+	Password     types.String `tfsdk:"password"`
+	ApiKey       types.String `tfsdk:"api_key"`
+	BearerToken  types.String `tfsdk:"bearer_token"`
+	TokenCommand types.List   `tfsdk:"token_command"`
+	SpaceID      types.String `tfsdk:"space_id"`
+
+	CAFile             types.String `tfsdk:"ca_file"`
+	CAPem              types.String `tfsdk:"ca_pem"`
+	ClientCert         types.String `tfsdk:"client_cert"`
+	ClientKey          types.String `tfsdk:"client_key"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+
+	MaxRetries   types.Int64  `tfsdk:"max_retries"`
+	RetryWaitMin types.String `tfsdk:"retry_wait_min"`
+	RetryWaitMax types.String `tfsdk:"retry_wait_max"`
+
+	RequestTimeout types.String `tfsdk:"request_timeout"`
+	TraceFile      types.String `tfsdk:"trace_file"`
+
+	MaxConcurrentRequests types.Int64   `tfsdk:"max_concurrent_requests"`
+	RequestsPerSecond     types.Float64 `tfsdk:"requests_per_second"`
+
+	CloudID      types.String `tfsdk:"cloud_id"`
+	Endpoint     types.String `tfsdk:"endpoint"`
+	ExtraHeaders types.Map    `tfsdk:"extra_headers"`
+	ProxyURL     types.String `tfsdk:"proxy_url"`
 }
 
 func (p *ElasticSiemProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -40,27 +70,112 @@ func (p *ElasticSiemProvider) Schema(ctx context.Context, req provider.SchemaReq
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"hostname": schema.StringAttribute{
-				MarkdownDescription: "The Kibana host name",
-				Required:            true,
+				MarkdownDescription: "The Kibana host name. Can also be set with the `KIBANA_HOST` environment variable. Conflicts with `endpoint` and `cloud_id`",
+				Optional:            true,
+			},
+			"cloud_id": schema.StringAttribute{
+				MarkdownDescription: "The Elastic Cloud ID of the deployment, found in the Elastic Cloud console. The Kibana host is decoded from it and reached with TLS on port 443, unless the Cloud ID includes another port. Can also be set with the `KIBANA_CLOUD_ID` environment variable. Conflicts with `hostname`, `port`, `tls` and `endpoint`",
+				Optional:            true,
+			},
+			"endpoint": schema.StringAttribute{
+				MarkdownDescription: "The full URL of Kibana, including the path it is served under behind a reverse proxy (e.g. `https://gw.example.com/kibana`). Can also be set with the `KIBANA_ENDPOINT` environment variable. Conflicts with `hostname`, `port` and `tls`",
+				Optional:            true,
+			},
+			"extra_headers": schema.MapAttribute{
+				MarkdownDescription: "Additional headers sent with every request, e.g. to route requests through a proxy. The `Authorization`, `Content-Type` and `kbn-xsrf` headers are set by the provider and cannot be overridden",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"proxy_url": schema.StringAttribute{
+				MarkdownDescription: "URL of the HTTP(S) proxy requests are sent through (e.g. `http://proxy.example.com:3128`). Can also be set with the `KIBANA_PROXY_URL` environment variable. Defaults to the `HTTPS_PROXY`, `HTTP_PROXY` and `NO_PROXY` environment variables",
+				Optional:            true,
 			},
 			"tls": schema.BoolAttribute{
-				MarkdownDescription: "Connect to host using TLS or unencrypted",
+				MarkdownDescription: "Connect to host using TLS or unencrypted. Can also be set with the `KIBANA_TLS` environment variable. Defaults to `true`. Conflicts with `endpoint` and `cloud_id`, even when set with the environment variable",
 				Optional:            true,
 			},
 			"port": schema.Int64Attribute{
-				MarkdownDescription: "Connect to host on a custom port",
+				MarkdownDescription: "Connect to host on a custom port. Can also be set with the `KIBANA_PORT` environment variable. Defaults to `443`. Conflicts with `endpoint` and `cloud_id`, even when set with the environment variable",
 				Optional:            true,
 			},
 			"user": schema.StringAttribute{
-				MarkdownDescription: "The username to authenticate to Kiaba and interact with the SIEM",
-				Required:            true,
+				MarkdownDescription: "The username to authenticate to Kibana and interact with the SIEM. Can also be set with the `KIBANA_USERNAME` environment variable. Conflicts with `api_key`, `bearer_token` and `token_command`",
+				Optional:            true,
 				Sensitive:           true,
 			},
 			"password": schema.StringAttribute{
-				MarkdownDescription: "The password to authenticate to Kiaba and interact with the SIEM",
-				Required:            true,
+				MarkdownDescription: "The password to authenticate to Kibana and interact with the SIEM. Can also be set with the `KIBANA_PASSWORD` environment variable. Conflicts with `api_key`, `bearer_token` and `token_command`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"api_key": schema.StringAttribute{
+				MarkdownDescription: "The base64 encoded API key to authenticate to Kibana (sent as `Authorization: ApiKey <key>`). Can also be set with the `KIBANA_API_KEY` environment variable. Conflicts with `user`, `password`, `bearer_token` and `token_command`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"bearer_token": schema.StringAttribute{
+				MarkdownDescription: "A bearer token to authenticate to Kibana (sent as `Authorization: Bearer <token>`), such as an OAuth/OIDC access token or an Elasticsearch service account token. Can also be set with the `KIBANA_BEARER_TOKEN` environment variable. Conflicts with `user`, `password` and `api_key`",
+				Optional:            true,
 				Sensitive:           true,
 			},
+			"token_command": schema.ListAttribute{
+				MarkdownDescription: "A command and its arguments printing a bearer token on its standard output (e.g. `[\"vault\", \"read\", \"-field=token\", \"kibana/token\"]`). It is run when `bearer_token` is not set, and to refresh the token when Kibana rejects it with a 401, in which case the request is sent again once. Conflicts with `user`, `password` and `api_key`",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"space_id": schema.StringAttribute{
+				MarkdownDescription: "The Kibana space resources are managed in, unless overridden by their own `space_id`. Can also be set with the `KIBANA_SPACE_ID` environment variable. Defaults to the default space",
+				Optional:            true,
+			},
+			"ca_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a PEM encoded CA bundle used to verify the Kibana certificate. Can also be set with the `KIBANA_CA_FILE` environment variable",
+				Optional:            true,
+			},
+			"ca_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA bundle used to verify the Kibana certificate. Can also be set with the `KIBANA_CA_PEM` environment variable",
+				Optional:            true,
+			},
+			"client_cert": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded client certificate, or the path to it, presented to Kibana. Requires `client_key`. Can also be set with the `KIBANA_CLIENT_CERT` environment variable",
+				Optional:            true,
+			},
+			"client_key": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded private key of the client certificate, or the path to it. Requires `client_cert`. Can also be set with the `KIBANA_CLIENT_KEY` environment variable",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				MarkdownDescription: "Skip the verification of the Kibana certificate. Use only for testing. Can also be set with the `KIBANA_INSECURE_SKIP_VERIFY` environment variable. Defaults to `false`",
+				Optional:            true,
+			},
+			"request_timeout": schema.StringAttribute{
+				MarkdownDescription: "Time limit of a single request to Kibana (e.g. `30s`, `2m`), `0s` disables it. Can also be set with the `KIBANA_REQUEST_TIMEOUT` environment variable. Defaults to `10s`",
+				Optional:            true,
+			},
+			"trace_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a file every request and response is appended to as JSON lines, with credentials and secrets masked. Requests are also logged with `TF_LOG=DEBUG` (bodies with `TF_LOG=TRACE`). Can also be set with the `KIBANA_TRACE_FILE` environment variable",
+				Optional:            true,
+			},
+			"max_retries": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of times a request is retried on connection errors, rate limiting (429) or server errors (5xx). POST requests are only retried when they did not reach Kibana. Can also be set with the `KIBANA_MAX_RETRIES` environment variable. Defaults to `3`",
+				Optional:            true,
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of requests sent to Kibana at the same time by all the resources, to protect small or shared clusters without lowering the Terraform parallelism. Can also be set with the `KIBANA_MAX_CONCURRENT_REQUESTS` environment variable. Defaults to `0` (unlimited)",
+				Optional:            true,
+			},
+			"requests_per_second": schema.Float64Attribute{
+				MarkdownDescription: "Maximum number of requests per second sent to Kibana by all the resources, retries included. Bursts of up to one second of requests are allowed. Can also be set with the `KIBANA_REQUESTS_PER_SECOND` environment variable. Defaults to `0` (unlimited)",
+				Optional:            true,
+			},
+			"retry_wait_min": schema.StringAttribute{
+				MarkdownDescription: "Minimum wait between retries (e.g. `500ms`), doubled on every attempt. Can also be set with the `KIBANA_RETRY_WAIT_MIN` environment variable. Defaults to `1s`",
+				Optional:            true,
+			},
+			"retry_wait_max": schema.StringAttribute{
+				MarkdownDescription: "Maximum wait between retries (e.g. `30s`), also capping the `Retry-After` header sent by Kibana. Can also be set with the `KIBANA_RETRY_WAIT_MAX` environment variable. Defaults to `30s`",
+				Optional:            true,
+			},
 		},
 	}
 }
@@ -75,42 +190,257 @@ func (p *ElasticSiemProvider) Configure(ctx context.Context, req provider.Config
 	}
 
 	// Configuration values are now available.
-	// if data.Endpoint.IsNull() { /* ... */ }
+	// Every attribute falls back to its environment variable when not set.
+	hostname := stringValueOrEnv(&resp.Diagnostics, "hostname", data.Hostname, envKibanaHost, "")
+	port := int(int64ValueOrEnv(&resp.Diagnostics, "port", data.Port, envKibanaPort, 443))
+	useTls := boolValueOrEnv(&resp.Diagnostics, "tls", data.UseTLS, envKibanaTLS, true)
+	username := stringValueOrEnv(&resp.Diagnostics, "user", data.Username, envKibanaUsername, "")
+	password := stringValueOrEnv(&resp.Diagnostics, "password", data.Password, envKibanaPassword, "")
+	apiKey := stringValueOrEnv(&resp.Diagnostics, "api_key", data.ApiKey, envKibanaApiKey, "")
+	bearerToken := stringValueOrEnv(&resp.Diagnostics, "bearer_token", data.BearerToken, envKibanaBearerToken, "")
+	var tokenCommand []string
+	if data.TokenCommand.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("token_command"),
+			"[Configure][Provider] Unknown Configuration Value",
+			"The provider cannot create the Kibana client as there is an unknown configuration value for token_command. "+
+				"Set the value statically in the configuration.",
+		)
+	} else if !data.TokenCommand.IsNull() {
+		resp.Diagnostics.Append(data.TokenCommand.ElementsAs(ctx, &tokenCommand, false)...)
+		if len(tokenCommand) == 0 || tokenCommand[0] == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token_command"),
+				"[Configure][Provider] Invalid Token Command",
+				"token_command must start with the command to run.",
+			)
+		}
+	}
+	spaceID := stringValueOrEnv(&resp.Diagnostics, "space_id", data.SpaceID, envKibanaSpaceID, "")
+	tlsInput := &helpers.TLSInput{
+		CAFile:             stringValueOrEnv(&resp.Diagnostics, "ca_file", data.CAFile, envKibanaCAFile, ""),
+		CAPem:              stringValueOrEnv(&resp.Diagnostics, "ca_pem", data.CAPem, envKibanaCAPem, ""),
+		ClientCert:         stringValueOrEnv(&resp.Diagnostics, "client_cert", data.ClientCert, envKibanaClientCert, ""),
+		ClientKey:          stringValueOrEnv(&resp.Diagnostics, "client_key", data.ClientKey, envKibanaClientKey, ""),
+		InsecureSkipVerify: boolValueOrEnv(&resp.Diagnostics, "insecure_skip_verify", data.InsecureSkipVerify, envKibanaInsecure, false),
+	}
+
+	retry := &helpers.RetryInput{
+		MaxRetries: int(int64ValueOrEnv(&resp.Diagnostics, "max_retries", data.MaxRetries, envKibanaMaxRetries, helpers.DefaultMaxRetries)),
+		WaitMin:    durationValueOrEnv(&resp.Diagnostics, "retry_wait_min", data.RetryWaitMin, envKibanaRetryWaitMin, helpers.DefaultRetryWaitMin),
+		WaitMax:    durationValueOrEnv(&resp.Diagnostics, "retry_wait_max", data.RetryWaitMax, envKibanaRetryWaitMax, helpers.DefaultRetryWaitMax),
+	}
+
+	traceFile := stringValueOrEnv(&resp.Diagnostics, "trace_file", data.TraceFile, envKibanaTraceFile, "")
+	cloudID := stringValueOrEnv(&resp.Diagnostics, "cloud_id", data.CloudID, envKibanaCloudID, "")
+	endpoint := stringValueOrEnv(&resp.Diagnostics, "endpoint", data.Endpoint, envKibanaEndpoint, "")
+	proxyURL := stringValueOrEnv(&resp.Diagnostics, "proxy_url", data.ProxyURL, envKibanaProxyURL, "")
+
+	var extraHeaders map[string]string
+	if data.ExtraHeaders.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("extra_headers"),
+			"[Configure][Provider] Unknown Configuration Value",
+			"The provider cannot create the Kibana client as there is an unknown configuration value for extra_headers. "+
+				"Set the value statically in the configuration.",
+		)
+	} else if !data.ExtraHeaders.IsNull() {
+		resp.Diagnostics.Append(data.ExtraHeaders.ElementsAs(ctx, &extraHeaders, false)...)
+	}
+	throttle := helpers.ThrottleInput{
+		MaxConcurrentRequests: int(int64ValueOrEnv(&resp.Diagnostics, "max_concurrent_requests", data.MaxConcurrentRequests, envKibanaMaxConcurrent, 0)),
+		RequestsPerSecond:     float64ValueOrEnv(&resp.Diagnostics, "requests_per_second", data.RequestsPerSecond, envKibanaRequestsPerSec, 0),
+	}
+	requestTimeout := durationValueOrEnv(&resp.Diagnostics, "request_timeout", data.RequestTimeout, envKibanaRequestTimeout, helpers.DefaultRequestTimeout)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if requestTimeout < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("request_timeout"),
+			"[Configure][Provider] Invalid Request Timeout",
+			"request_timeout must be zero (no time limit) or greater.",
+		)
+	}
+	// 0s disables the time limit, the client applies its default to a zero timeout
+	if requestTimeout == 0 {
+		requestTimeout = helpers.NoRequestTimeout
+	}
+
+	if retry.MaxRetries < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_retries"),
+			"[Configure][Provider] Invalid Retry Configuration",
+			"max_retries must be zero or greater.",
+		)
+	}
 
-	hostname := "localhost"
-	port := 443
-	useTls := true
-	username := "elastic"
-	password := "elastic"
+	if throttle.MaxConcurrentRequests < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_concurrent_requests"),
+			"[Configure][Provider] Invalid Throttling Configuration",
+			"max_concurrent_requests must be zero (unlimited) or greater.",
+		)
+	}
 
-	if !data.Hostname.IsNull() {
-		hostname = data.Hostname.ValueString()
+	if throttle.RequestsPerSecond < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("requests_per_second"),
+			"[Configure][Provider] Invalid Throttling Configuration",
+			"requests_per_second must be zero (unlimited) or greater.",
+		)
 	}
 
-	if !data.Port.IsNull() {
-		port = int(data.Port.ValueInt64())
+	if retry.WaitMin > retry.WaitMax {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry_wait_min"),
+			"[Configure][Provider] Invalid Retry Configuration",
+			fmt.Sprintf("retry_wait_min (%s) cannot be greater than retry_wait_max (%s).", retry.WaitMin, retry.WaitMax),
+		)
 	}
 
-	if !data.UseTLS.IsNull() {
-		useTls = data.UseTLS.ValueBool()
+	for name := range extraHeaders {
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization", "Content-Type", "Kbn-Xsrf":
+			resp.Diagnostics.AddAttributeError(
+				path.Root("extra_headers"),
+				"[Configure][Provider] Invalid Extra Header",
+				fmt.Sprintf("The %s header is set by the provider and cannot be overridden.", name),
+			)
+		}
+	}
+
+	if proxyURL != "" {
+		if u, err := url.Parse(proxyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("proxy_url"),
+				"[Configure][Provider] Invalid Proxy URL",
+				fmt.Sprintf("Expected an absolute URL such as http://proxy.example.com:3128, got: %s", proxyURL),
+			)
+		}
+	}
+
+	// port and tls only apply to hostname, they are rejected as well when they come from the environment
+	portOrTLSSet := isSetOrEnv(data.Port, envKibanaPort) || isSetOrEnv(data.UseTLS, envKibanaTLS)
+	if cloudID != "" {
+		if hostname != "" || endpoint != "" || portOrTLSSet {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cloud_id"),
+				"[Configure][Provider] Conflicting Cloud ID Configuration",
+				fmt.Sprintf("cloud_id cannot be combined with hostname, port, tls or endpoint (or the %s, %s, %s and %s environment variables), "+
+					"the Kibana host is read from the Cloud ID.", envKibanaHost, envKibanaPort, envKibanaTLS, envKibanaEndpoint),
+			)
+		} else if cloudHost, cloudPort, err := helpers.ParseCloudID(cloudID); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cloud_id"),
+				"[Configure][Provider] Invalid Cloud ID",
+				err.Error(),
+			)
+		} else {
+			hostname, port, useTls = cloudHost, cloudPort, true
+		}
+	} else if endpoint != "" {
+		if _, err := helpers.ParseEndpoint(endpoint); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("endpoint"),
+				"[Configure][Provider] Invalid Endpoint",
+				err.Error(),
+			)
+		}
+		if hostname != "" || portOrTLSSet {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("endpoint"),
+				"[Configure][Provider] Conflicting Endpoint Configuration",
+				fmt.Sprintf("endpoint cannot be combined with hostname, port or tls (or the %s, %s and %s environment variables). "+
+					"Include the scheme and port in the endpoint URL instead.", envKibanaHost, envKibanaPort, envKibanaTLS),
+			)
+		}
+	} else if hostname == "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("hostname"),
+			"[Configure][Provider] Missing Kibana Host",
+			fmt.Sprintf("The provider cannot create the Kibana client as there is no Kibana host configured. "+
+				"Set the hostname, endpoint or cloud_id attribute, or the %s, %s or %s environment variable.", envKibanaHost, envKibanaEndpoint, envKibanaCloudID),
+		)
 	}
 
-	if !data.Username.IsNull() {
-		username = data.Username.ValueString()
+	// Exactly one authentication method must be configured
+	useBasicAuth := username != "" || password != ""
+	useBearerToken := bearerToken != "" || len(tokenCommand) > 0
+	authMethods := 0
+	for _, used := range []bool{useBasicAuth, apiKey != "", useBearerToken} {
+		if used {
+			authMethods++
+		}
+	}
+	if authMethods > 1 {
+		resp.Diagnostics.AddError(
+			"[Configure][Provider] Conflicting Authentication Methods",
+			"More than one of user/password, api_key and bearer_token/token_command are set. Configure exactly one authentication method.",
+		)
+	} else if useBasicAuth && (username == "" || password == "") {
+		missing := "password"
+		if username == "" {
+			missing = "user"
+		}
+		resp.Diagnostics.AddAttributeError(
+			path.Root(missing),
+			"[Configure][Provider] Incomplete Basic Authentication",
+			"Both user and password must be set to use basic authentication.",
+		)
+	} else if authMethods == 0 {
+		resp.Diagnostics.AddError(
+			"[Configure][Provider] Missing Authentication Method",
+			fmt.Sprintf("None of user/password, api_key, bearer_token or token_command are set. Configure exactly one authentication method, "+
+				"either in the provider block or through the %s/%s, %s or %s environment variables.", envKibanaUsername, envKibanaPassword, envKibanaApiKey, envKibanaBearerToken),
+		)
 	}
 
-	if !data.Password.IsNull() {
-		password = data.Password.ValueString()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
 	// Example client configuration for data sources and resources
-	client := helpers.NewClient(&helpers.NewClientInput{
-		Hostname: hostname,
-		Port:     port,
-		UseTls:   useTls,
-		Username: username,
-		Password: password,
+	client, err := helpers.NewClient(&helpers.NewClientInput{
+		Hostname:     hostname,
+		Port:         port,
+		UseTls:       useTls,
+		Username:     username,
+		Password:     password,
+		ApiKey:       apiKey,
+		TLS:          tlsInput,
+		SpaceID:      spaceID,
+		Retry:        retry,
+		Timeout:      requestTimeout,
+		TraceFile:    traceFile,
+		Endpoint:     endpoint,
+		ExtraHeaders: extraHeaders,
+		ProxyURL:     proxyURL,
+		Throttle:     throttle,
+		BearerToken:  bearerToken,
+		TokenCommand: tokenCommand,
 	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"[Configure][Provider] Client Error",
+			fmt.Sprintf("Unable to create the Kibana client, got error: %s", err),
+		)
+		return
+	}
+
+	// The version adapts the payloads to the server, an unknown version disables these adaptations
+	version, err := client.DetectVersion(ctx)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"[Configure][Provider] Unknown Kibana Version",
+			fmt.Sprintf("Unable to read the Kibana version from the status API, fields will be sent without checking "+
+				"that the server supports them. Got error: %s", err),
+		)
+	} else {
+		tflog.Debug(ctx, "Detected Kibana version", map[string]interface{}{"version": version.String()})
+	}
 
 	resp.DataSourceData = client
 	resp.ResourceData = client
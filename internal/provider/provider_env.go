@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Environment variables used as fallbacks for the provider configuration
+const (
+	envKibanaHost        = "KIBANA_HOST"
+	envKibanaPort        = "KIBANA_PORT"
+	envKibanaTLS         = "KIBANA_TLS"
+	envKibanaUsername    = "KIBANA_USERNAME"
+	envKibanaPassword    = "KIBANA_PASSWORD"
+	envKibanaApiKey      = "KIBANA_API_KEY"
+	envKibanaBearerToken = "KIBANA_BEARER_TOKEN"
+	envKibanaSpaceID     = "KIBANA_SPACE_ID"
+
+	envKibanaCAFile     = "KIBANA_CA_FILE"
+	envKibanaCAPem      = "KIBANA_CA_PEM"
+	envKibanaClientCert = "KIBANA_CLIENT_CERT"
+	envKibanaClientKey  = "KIBANA_CLIENT_KEY"
+	envKibanaInsecure   = "KIBANA_INSECURE_SKIP_VERIFY"
+
+	envKibanaMaxRetries   = "KIBANA_MAX_RETRIES"
+	envKibanaRetryWaitMin = "KIBANA_RETRY_WAIT_MIN"
+	envKibanaRetryWaitMax = "KIBANA_RETRY_WAIT_MAX"
+
+	envKibanaRequestTimeout = "KIBANA_REQUEST_TIMEOUT"
+	envKibanaMaxConcurrent  = "KIBANA_MAX_CONCURRENT_REQUESTS"
+	envKibanaRequestsPerSec = "KIBANA_REQUESTS_PER_SECOND"
+	envKibanaTraceFile      = "KIBANA_TRACE_FILE"
+
+	envKibanaCloudID  = "KIBANA_CLOUD_ID"
+	envKibanaEndpoint = "KIBANA_ENDPOINT"
+	envKibanaProxyURL = "KIBANA_PROXY_URL"
+)
+
+// addUnknownValueError reports a provider attribute whose value is not known during configuration
+func addUnknownValueError(diags *diag.Diagnostics, attribute string, env string) {
+	diags.AddAttributeError(
+		path.Root(attribute),
+		"[Configure][Provider] Unknown Configuration Value",
+		fmt.Sprintf("The provider cannot create the Kibana client as there is an unknown configuration value for %s. "+
+			"Either set the value statically in the configuration, or use the %s environment variable.", attribute, env),
+	)
+}
+
+// stringValueOrEnv returns the configured value, the environment variable or the fallback, in that order
+func stringValueOrEnv(diags *diag.Diagnostics, attribute string, value types.String, env string, fallback string) string {
+	if value.IsUnknown() {
+		addUnknownValueError(diags, attribute, env)
+		return fallback
+	}
+	if !value.IsNull() {
+		return value.ValueString()
+	}
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// boolValueOrEnv returns the configured value, the environment variable or the fallback, in that order
+func boolValueOrEnv(diags *diag.Diagnostics, attribute string, value types.Bool, env string, fallback bool) bool {
+	if value.IsUnknown() {
+		addUnknownValueError(diags, attribute, env)
+		return fallback
+	}
+	if !value.IsNull() {
+		return value.ValueBool()
+	}
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root(attribute),
+				"[Configure][Provider] Invalid Environment Variable",
+				fmt.Sprintf("Unable to parse %s=%q as a boolean: %s", env, v, err),
+			)
+			return fallback
+		}
+		return b
+	}
+	return fallback
+}
+
+// int64ValueOrEnv returns the configured value, the environment variable or the fallback, in that order
+func int64ValueOrEnv(diags *diag.Diagnostics, attribute string, value types.Int64, env string, fallback int64) int64 {
+	if value.IsUnknown() {
+		addUnknownValueError(diags, attribute, env)
+		return fallback
+	}
+	if !value.IsNull() {
+		return value.ValueInt64()
+	}
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root(attribute),
+				"[Configure][Provider] Invalid Environment Variable",
+				fmt.Sprintf("Unable to parse %s=%q as an integer: %s", env, v, err),
+			)
+			return fallback
+		}
+		return i
+	}
+	return fallback
+}
+
+// isSetOrEnv reports whether an attribute is configured or set by its environment variable
+func isSetOrEnv(value attr.Value, env string) bool {
+	if !value.IsNull() {
+		return true
+	}
+	v, ok := os.LookupEnv(env)
+	return ok && v != ""
+}
+
+// float64ValueOrEnv returns the configured value, the environment variable or the fallback, in that order
+func float64ValueOrEnv(diags *diag.Diagnostics, attribute string, value types.Float64, env string, fallback float64) float64 {
+	if value.IsUnknown() {
+		addUnknownValueError(diags, attribute, env)
+		return fallback
+	}
+	if !value.IsNull() {
+		return value.ValueFloat64()
+	}
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root(attribute),
+				"[Configure][Provider] Invalid Environment Variable",
+				fmt.Sprintf("Unable to parse %s=%q as a number: %s", env, v, err),
+			)
+			return fallback
+		}
+		return f
+	}
+	return fallback
+}
+
+// durationValueOrEnv returns the configured duration, the environment variable or the fallback, in that order
+func durationValueOrEnv(diags *diag.Diagnostics, attribute string, value types.String, env string, fallback time.Duration) time.Duration {
+	v := stringValueOrEnv(diags, attribute, value, env, "")
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		diags.AddAttributeError(
+			path.Root(attribute),
+			"[Configure][Provider] Invalid Duration",
+			fmt.Sprintf("Unable to parse %q as a positive duration (e.g. \"500ms\", \"2s\", \"1m\")", v),
+		)
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestProviderValueOrEnv(t *testing.T) {
+	t.Setenv(envKibanaHost, "kibana.example.com")
+	t.Setenv(envKibanaPort, "5601")
+	t.Setenv(envKibanaTLS, "false")
+
+	var diags diag.Diagnostics
+
+	if v := stringValueOrEnv(&diags, "hostname", types.StringNull(), envKibanaHost, ""); v != "kibana.example.com" {
+		t.Errorf("Expected hostname from environment, got '%s'", v)
+	}
+	if v := stringValueOrEnv(&diags, "hostname", types.StringValue("configured"), envKibanaHost, ""); v != "configured" {
+		t.Errorf("Expected configured hostname to take precedence, got '%s'", v)
+	}
+	if v := int64ValueOrEnv(&diags, "port", types.Int64Null(), envKibanaPort, 443); v != 5601 {
+		t.Errorf("Expected port from environment, got %d", v)
+	}
+	if v := boolValueOrEnv(&diags, "tls", types.BoolNull(), envKibanaTLS, true); v {
+		t.Errorf("Expected tls from environment to be false")
+	}
+	if v := stringValueOrEnv(&diags, "user", types.StringNull(), envKibanaUsername, ""); v != "" {
+		t.Errorf("Expected empty fallback user, got '%s'", v)
+	}
+	if diags.HasError() {
+		t.Fatalf("Unexpected diagnostics: %v", diags)
+	}
+
+	t.Setenv(envKibanaRequestsPerSec, "2.5")
+	if v := float64ValueOrEnv(&diags, "requests_per_second", types.Float64Null(), envKibanaRequestsPerSec, 0); v != 2.5 {
+		t.Errorf("Expected requests_per_second from environment, got %f", v)
+	}
+	if diags.HasError() {
+		t.Fatalf("Unexpected diagnostics: %v", diags)
+	}
+
+	t.Setenv(envKibanaPort, "not-a-port")
+	int64ValueOrEnv(&diags, "port", types.Int64Null(), envKibanaPort, 443)
+	if !diags.HasError() {
+		t.Errorf("Expected an error for an invalid %s", envKibanaPort)
+	}
+
+	diags = nil
+	stringValueOrEnv(&diags, "hostname", types.StringUnknown(), envKibanaHost, "")
+	if !diags.HasError() {
+		t.Errorf("Expected an error for an unknown hostname")
+	}
+}
+
+func TestProviderConfigurePortAndTLSFromEnv(t *testing.T) {
+	ctx := context.Background()
+	p := &ElasticSiemProvider{}
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+
+	for _, test := range []struct {
+		attribute, value, env, summary string
+	}{
+		{attribute: "endpoint", value: "https://gw.example.com/kibana", env: envKibanaPort, summary: "[Configure][Provider] Conflicting Endpoint Configuration"},
+		{attribute: "endpoint", value: "https://gw.example.com/kibana", env: envKibanaTLS, summary: "[Configure][Provider] Conflicting Endpoint Configuration"},
+		{attribute: "cloud_id", value: "deployment:ZXhhbXBsZS5jb20kZXMka2liYW5h", env: envKibanaPort, summary: "[Configure][Provider] Conflicting Cloud ID Configuration"},
+	} {
+		for _, env := range []string{envKibanaHost, envKibanaPort, envKibanaTLS, envKibanaEndpoint, envKibanaCloudID} {
+			t.Setenv(env, "")
+		}
+		t.Setenv(test.env, map[string]string{envKibanaPort: "5601", envKibanaTLS: "false"}[test.env])
+
+		config := tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+		state := tfsdk.State{Schema: config.Schema, Raw: config.Raw}
+		for attribute, value := range map[string]string{test.attribute: test.value, "api_key": "key"} {
+			if diags := state.SetAttribute(ctx, path.Root(attribute), value); diags.HasError() {
+				t.Fatal(diags)
+			}
+		}
+		config.Raw = state.Raw
+
+		var resp provider.ConfigureResponse
+		p.Configure(ctx, provider.ConfigureRequest{Config: config}, &resp)
+		if resp.Diagnostics.ErrorsCount() != 1 || resp.Diagnostics.Errors()[0].Summary() != test.summary {
+			t.Errorf("Expected %s with %s, got: %v", test.summary, test.env, resp.Diagnostics)
+		}
+	}
+}
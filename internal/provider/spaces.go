@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// spaceIDAttribute is the space_id schema attribute shared by all resources
+func spaceIDAttribute(object string) schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "The Kibana space of the " + object + ". Defaults to the provider `space_id` or the default space. Changing it forces a new resource",
+		Optional:            true,
+		Computed:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplaceIfConfigured(),
+		},
+	}
+}
+
+// modifyPlanSpaceID plans the provider space when space_id is not configured on the resource,
+// and forces a replacement when it differs from the space the resource lives in.
+func modifyPlanSpaceID(ctx context.Context, client *helpers.Client, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy or when the provider is not configured yet
+	if req.Plan.Raw.IsNull() || client == nil {
+		return
+	}
+
+	var configSpaceID types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("space_id"), &configSpaceID)...)
+	if resp.Diagnostics.HasError() || !configSpaceID.IsNull() {
+		return
+	}
+
+	spaceID := client.SpaceID()
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("space_id"), spaceID)...)
+
+	if req.State.Raw.IsNull() {
+		return
+	}
+
+	var stateSpaceID types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("space_id"), &stateSpaceID)...)
+	if !stateSpaceID.IsNull() && stateSpaceID.ValueString() != spaceID {
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("space_id"))
+	}
+}
+
+// spaceClient returns the client of the resource space and stores the resolved space in spaceID
+func spaceClient(client *helpers.Client, spaceID *types.String) *helpers.Client {
+	if spaceID.IsNull() || spaceID.IsUnknown() || spaceID.ValueString() == "" {
+		*spaceID = types.StringValue(client.SpaceID())
+	}
+	return client.WithSpace(spaceID.ValueString())
+}
+
+// importStateWithSpace imports an object by "<id>" or "<space_id>/<id>"
+func importStateWithSpace(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	id := req.ID
+	if spaceID, objectID, found := strings.Cut(req.ID, "/"); found {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("space_id"), spaceID)...)
+		id = objectID
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+}
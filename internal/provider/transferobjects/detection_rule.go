@@ -2,40 +2,60 @@ package transferobjects
 
 import "time"
 
+type ThreatEntry struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+type ThreatTechnique struct {
+	ThreatEntry
+	Subtechnique []ThreatEntry `json:"subtechnique,omitempty"`
+}
+
 type ThreatItem struct {
-	Framework string `json:"framework,omitempty"`
-	Tactic    struct {
-		ID        string `json:"id,omitempty"`
-		Name      string `json:"name,omitempty"`
-		Reference string `json:"reference,omitempty"`
-	} `json:"tactic,omitempty"`
-	Technique []struct {
-		ID           string `json:"id,omitempty"`
-		Name         string `json:"name,omitempty"`
-		Reference    string `json:"reference,omitempty"`
-		Subtechnique []struct {
-			ID        string `json:"id,omitempty"`
-			Name      string `json:"name,omitempty"`
-			Reference string `json:"reference,omitempty"`
-		} `json:"subtechnique,omitempty"`
-	} `json:"technique,omitempty"`
+	Framework string            `json:"framework,omitempty"`
+	Tactic    ThreatEntry       `json:"tactic,omitempty"`
+	Technique []ThreatTechnique `json:"technique,omitempty"`
+}
+
+type ThreatMappingEntry struct {
+	Field string `json:"field,omitempty"`
+	Type  string `json:"type,omitempty"`
+	// Value is required by Kibana, even when it is empty
+	Value string `json:"value"`
 }
 
 type ThreatMapping struct {
-	Entries []struct {
-		Field string `json:"field,omitempty"`
-		Type  string `json:"type,omitempty"`
-		Value string `json:"value,omitempty"`
-	} `json:"entries,omitempty"`
+	Entries []ThreatMappingEntry `json:"entries,omitempty"`
+}
+
+type ThresholdCardinality struct {
+	Field string `json:"field,omitempty"`
+	Value int    `json:"value,omitempty"`
 }
 
 type RuleThreshold struct {
-	Cardinality []struct {
-		Field string `json:"field,omitempty"`
-		Value int    `json:"value,omitempty"`
-	} `json:"cardinality,omitempty"`
-	Field []string `json:"field,omitempty"`
-	Value int      `json:"value,omitempty"`
+	Cardinality []ThresholdCardinality `json:"cardinality,omitempty"`
+	Field       []string               `json:"field,omitempty"`
+	Value       int                    `json:"value,omitempty"`
+}
+
+type AlertSuppressionDuration struct {
+	Value int    `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+type AlertSuppression struct {
+	GroupBy               []string                  `json:"group_by,omitempty"`
+	Duration              *AlertSuppressionDuration `json:"duration,omitempty"`
+	MissingFieldsStrategy string                    `json:"missing_fields_strategy,omitempty"`
+}
+
+// ResponseAction runs an Osquery query (.osquery) or an Elastic Defend action (.endpoint) when the rule creates alerts
+type ResponseAction struct {
+	ActionTypeID string                 `json:"action_type_id,omitempty"`
+	Params       map[string]interface{} `json:"params,omitempty"`
 }
 
 type ExecutionHistoryItem struct {
@@ -55,12 +75,14 @@ type ExecutionHistoryItem struct {
 type RiskScoreMapping struct {
 	Field    string `json:"field,omitempty"`
 	Operator string `json:"operator,omitempty"`
-	Value    string `json:"value,omitempty"`
+	// Value is required by Kibana, even when it is empty
+	Value string `json:"value"`
 }
 
 type SeverityMapping struct {
-	Field    string `json:"field,omitempty"`
-	Value    string `json:"value,omitempty"`
+	Field string `json:"field,omitempty"`
+	// Value is required by Kibana, even when it is empty
+	Value    string `json:"value"`
 	Operator string `json:"operator,omitempty"`
 	Severity string `json:"severity,omitempty"`
 }
@@ -98,64 +120,69 @@ type DetectionRuleResponse struct {
 	CreatedBy        string               `json:"created_by,omitempty"`
 	ExecutionSummary ExecutionHistoryItem `json:"execution_summary,omitempty"`
 	Meta             MetaItem             `json:"meta,omitempty"`
+	Revision         int                  `json:"revision,omitempty"`
 	UpdatedAt        time.Time            `json:"updated_at,omitempty"`
 }
 
+// DetectionRule holds the fields of every rule type. ES|QL rules (type esql) select their indices in the FROM command
+// of Query, written in the esql Language, and have no Index.
 type DetectionRule struct {
-	Actions             []ActionItem        `json:"actions,omitempty"`
-	AnomalyThreshold    int                 `json:"anomaly_threshold,omitempty"`
-	Author              []string            `json:"author,omitempty"`
-	BuildingBlockTYpe   string              `json:"building_block_type,omitempty"`
-	Description         string              `json:"description,omitempty"`
-	Enabled             *bool               `json:"enabled,omitempty"` // bool values need to be pointers to include false
-	EventCategoryField  string              `json:"event_category_field,omitempty"`
-	ExceptionsList      []ExceptionListItem `json:"exceptions_list"`
-	FalsePositives      []interface{}       `json:"false_positives,omitempty"`
-	Filters             []interface{}       `json:"filters,omitempty"`
-	From                string              `json:"from,omitempty"`
-	ID                  string              `json:"id,omitempty"`
-	Immutable           *bool               `json:"immutable,omitempty"` // bool values need to be pointers to include false
-	Index               []string            `json:"index,omitempty"`
-	Interval            string              `json:"interval,omitempty"`
-	InvestigationFields []string            `json:"investigation_fields,omitempty"`
-	Language            string              `json:"language,omitempty"`
-	License             string              `json:"license,omitempty"`
-	HistoryWindowStart  string              `json:"history_window_start,omitempty"`
-	MachineLeanJID      []string            `json:"machine_learning_job_id,omitempty"`
-	MaxSignals          int                 `json:"max_signals,omitempty"`
-	Name                string              `json:"name,omitempty"`
-	NewTermsFields      []string            `json:"new_terms_fields,omitempty"`
-	Note                string              `json:"note,omitempty"`
-	OutputIndex         string              `json:"output_index,omitempty"`
-	Query               string              `json:"query,omitempty"`
-	References          []interface{}       `json:"references,omitempty"`
-	RelatedIntegrations []interface{}       `json:"related_integrations,omitempty"`
-	RequiredFields      []interface{}       `json:"required_fields,omitempty"`
-	RiskScore           int                 `json:"risk_score,omitempty"`
-	RiskScoreMapping    []RiskScoreMapping  `json:"risk_score_mapping,omitempty"`
-	RuleID              string              `json:"rule_id,omitempty"`
-	RuleNameOverride    string              `json:"rule_name_override,omitempty"`
-	SaveId              string              `json:"saved_id,omitempty"`
-	Setup               string              `json:"setup,omitempty"`
-	Severity            string              `json:"severity,omitempty"`
-	SeverityMapping     []SeverityMapping   `json:"severity_mapping,omitempty"`
-	Tags                []string            `json:"tags,omitempty"`
-	Threat              []ThreatItem        `json:"threat,omitempty"`
-	ThreatFilters       []interface{}       `json:"threat_filters,omitempty"`
-	ThreatIndex         []string            `json:"threat_index,omitempty"`
-	ThreatIndicatorPath string              `json:"threat_indicator_path,omitempty"`
-	ThreatQuery         string              `json:"threat_query,omitempty"`
-	ThreatLanguage      string              `json:"threat_language,omitempty"`
-	ThreatMapping       []ThreatMapping     `json:"threat_mapping,omitempty"`
-	Threshold           RuleThreshold       `json:"threshold,omitempty"`
-	Throttle            string              `json:"throttle,omitempty"`
-	TiebreakerField     string              `json:"tiebreaker_field,omitempty"`
-	TimestampField      string              `json:"timestamp_field,omitempty"`
-	TimeStampOverride   string              `json:"timestamp_override,omitempty"`
-	TimelineID          string              `json:"timeline_id,omitempty"`
-	TimelineTitle       string              `json:"timeline_title,omitempty"`
-	To                  string              `json:"to,omitempty"`
-	Type                string              `json:"type,omitempty"`
-	UpdatedBy           string              `json:"updated_by,omitempty"`
-	Version             int                 `json:"version,omitempty"`
+	Actions             []ActionItem         `json:"actions,omitempty"`
+	AlertSuppression    *AlertSuppression    `json:"alert_suppression,omitempty"`
+	AnomalyThreshold    int                  `json:"anomaly_threshold,omitempty"`
+	Author              []string             `json:"author,omitempty"`
+	BuildingBlockTYpe   string               `json:"building_block_type,omitempty"`
+	Description         string               `json:"description,omitempty"`
+	Enabled             *bool                `json:"enabled,omitempty"` // bool values need to be pointers to include false
+	EventCategoryField  string               `json:"event_category_field,omitempty"`
+	ExceptionsList      []ExceptionListItem  `json:"exceptions_list"`
+	FalsePositives      []interface{}        `json:"false_positives,omitempty"`
+	Filters             []interface{}        `json:"filters,omitempty"`
+	From                string               `json:"from,omitempty"`
+	ID                  string               `json:"id,omitempty"`
+	Immutable           *bool                `json:"immutable,omitempty"` // bool values need to be pointers to include false
+	Index               []string             `json:"index,omitempty"`
+	Interval            string               `json:"interval,omitempty"`
+	InvestigationFields *InvestigationFields `json:"investigation_fields,omitempty"`
+	Language            string               `json:"language,omitempty"` // kuery, lucene, eql or esql
+	License             string               `json:"license,omitempty"`
+	HistoryWindowStart  string               `json:"history_window_start,omitempty"`
+	MachineLeanJID      []string             `json:"machine_learning_job_id,omitempty"`
+	MaxSignals          int                  `json:"max_signals,omitempty"`
+	Name                string               `json:"name,omitempty"`
+	NewTermsFields      []string             `json:"new_terms_fields,omitempty"`
+	Note                string               `json:"note,omitempty"`
+	OutputIndex         string               `json:"output_index,omitempty"`
+	Query               string               `json:"query,omitempty"`
+	References          []interface{}        `json:"references,omitempty"`
+	RelatedIntegrations []interface{}        `json:"related_integrations,omitempty"`
+	RequiredFields      []interface{}        `json:"required_fields,omitempty"`
+	ResponseActions     []ResponseAction     `json:"response_actions,omitempty"`
+	RiskScore           int                  `json:"risk_score,omitempty"`
+	RiskScoreMapping    []RiskScoreMapping   `json:"risk_score_mapping,omitempty"`
+	RuleID              string               `json:"rule_id,omitempty"`
+	RuleNameOverride    string               `json:"rule_name_override,omitempty"`
+	SaveId              string               `json:"saved_id,omitempty"`
+	Setup               string               `json:"setup,omitempty"`
+	Severity            string               `json:"severity,omitempty"`
+	SeverityMapping     []SeverityMapping    `json:"severity_mapping,omitempty"`
+	Tags                []string             `json:"tags,omitempty"`
+	Threat              []ThreatItem         `json:"threat,omitempty"`
+	ThreatFilters       []interface{}        `json:"threat_filters,omitempty"`
+	ThreatIndex         []string             `json:"threat_index,omitempty"`
+	ThreatIndicatorPath string               `json:"threat_indicator_path,omitempty"`
+	ThreatQuery         string               `json:"threat_query,omitempty"`
+	ThreatLanguage      string               `json:"threat_language,omitempty"`
+	ThreatMapping       []ThreatMapping      `json:"threat_mapping,omitempty"`
+	Threshold           RuleThreshold        `json:"threshold,omitempty"`
+	Throttle            string               `json:"throttle,omitempty"`
+	TiebreakerField     string               `json:"tiebreaker_field,omitempty"`
+	TimestampField      string               `json:"timestamp_field,omitempty"`
+	TimeStampOverride   string               `json:"timestamp_override,omitempty"`
+	TimelineID          string               `json:"timeline_id,omitempty"`
+	TimelineTitle       string               `json:"timeline_title,omitempty"`
+	To                  string               `json:"to,omitempty"`
+	Type                string               `json:"type,omitempty"`
+	UpdatedBy           string               `json:"updated_by,omitempty"`
+	Version             int                  `json:"version,omitempty"`
 }
@@ -0,0 +1,56 @@
+package transferobjects
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// InvestigationFields are the fields shown when investigating an alert of the rule.
+// Kibana 8.10 sends them as an array of field names while later versions use an object
+// with a field_names array, both formats are accepted.
+type InvestigationFields struct {
+	FieldNames []string `json:"field_names"`
+	// legacy is set when the fields are encoded as an array
+	legacy bool
+}
+
+func (f *InvestigationFields) UnmarshalJSON(data []byte) error {
+	var fieldNames []string
+	if err := json.Unmarshal(data, &fieldNames); err == nil {
+		f.FieldNames = fieldNames
+		f.legacy = true
+		return nil
+	}
+	var object struct {
+		FieldNames []string `json:"field_names"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("investigation_fields must be an array of field names or an object with field_names: %w", err)
+	}
+	f.FieldNames = object.FieldNames
+	f.legacy = false
+	return nil
+}
+
+func (f InvestigationFields) MarshalJSON() ([]byte, error) {
+	fieldNames := f.FieldNames
+	if fieldNames == nil {
+		fieldNames = []string{}
+	}
+	if f.legacy {
+		return json.Marshal(fieldNames)
+	}
+	return json.Marshal(struct {
+		FieldNames []string `json:"field_names"`
+	}{fieldNames})
+}
+
+// IsLegacy reports whether the fields are encoded as an array
+func (f *InvestigationFields) IsLegacy() bool {
+	return f.legacy
+}
+
+// SetLegacy selects whether the fields are encoded as an array or as an object
+func (f *InvestigationFields) SetLegacy(legacy bool) {
+	f.legacy = legacy
+}
@@ -0,0 +1,47 @@
+package transferobjects
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// UnknownFields returns the fields of a JSON object which have no field in the struct v, such as the fields added
+// by a Kibana release the transfer objects do not model yet
+func UnknownFields(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name := range jsonFieldNames(reflect.TypeOf(v)) {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+// jsonFieldNames returns the JSON names of the fields of a struct type, including the fields of its embedded structs
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embedded := range jsonFieldNames(field.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// fieldRequirement is the first Kibana version supporting a field, or a value of a field when value is set
+type fieldRequirement struct {
+	field   string
+	value   string
+	version *helpers.Version
+}
+
+// detectionRuleRequirements lists the rule fields and rule types which are not supported by every Kibana version
+var detectionRuleRequirements = []fieldRequirement{
+	{field: "type", value: "eql", version: helpers.MustParseVersion("7.9.0")},
+	{field: "type", value: "threat_match", version: helpers.MustParseVersion("7.10.0")},
+	{field: "type", value: "new_terms", version: helpers.MustParseVersion("8.4.0")},
+	{field: "type", value: "esql", version: helpers.MustParseVersion("8.13.0")},
+	{field: "related_integrations", version: helpers.MustParseVersion("8.3.0")},
+	{field: "required_fields", version: helpers.MustParseVersion("8.3.0")},
+	{field: "history_window_start", version: helpers.MustParseVersion("8.4.0")},
+	{field: "new_terms_fields", version: helpers.MustParseVersion("8.4.0")},
+	{field: "alert_suppression", version: helpers.MustParseVersion("8.8.0")},
+	{field: "response_actions", version: helpers.MustParseVersion("8.8.0")},
+	{field: "investigation_fields", version: helpers.MustParseVersion("8.10.0")},
+}
+
+// exceptionItemRequirements lists the exception item fields which are not supported by every Kibana version
+var exceptionItemRequirements = []fieldRequirement{
+	{field: "expire_time", version: helpers.MustParseVersion("8.7.0")},
+}
+
+// investigationFieldsObjectVersion is the first Kibana version expecting investigation_fields as an object
+var investigationFieldsObjectVersion = helpers.MustParseVersion("8.11.0")
+
+// serverDefault is the value Kibana gives to a field left out of a request, starting with a Kibana version.
+// Nested fields are separated by dots. The version is nil for the defaults of every supported release,
+// and ruleTypes is empty for the defaults of every rule type.
+type serverDefault struct {
+	field     string
+	value     string
+	version   *helpers.Version
+	ruleTypes []string
+}
+
+// detectionRuleDefaults lists the rule fields filled in by Kibana when a rule is created or updated.
+// A later entry of the same field overrides the earlier ones from its version on.
+var detectionRuleDefaults = []serverDefault{
+	{field: "author", value: `[]`},
+	{field: "enabled", value: `true`},
+	{field: "false_positives", value: `[]`},
+	{field: "from", value: `"now-6m"`},
+	{field: "interval", value: `"5m"`},
+	{field: "language", value: `"kuery"`, ruleTypes: []string{"query", "saved_query", "threat_match", "threshold", "new_terms"}},
+	{field: "max_signals", value: `100`},
+	{field: "output_index", value: `""`},
+	{field: "references", value: `[]`},
+	{field: "risk_score_mapping", value: `[]`},
+	{field: "severity_mapping", value: `[]`},
+	{field: "threat", value: `[]`},
+	{field: "related_integrations", value: `[]`, version: helpers.MustParseVersion("8.3.0")},
+	{field: "required_fields", value: `[]`, version: helpers.MustParseVersion("8.3.0")},
+	{field: "setup", value: `""`, version: helpers.MustParseVersion("8.3.0")},
+	{field: "alert_suppression.missing_fields_strategy", value: `"suppress"`, version: helpers.MustParseVersion("8.8.0")},
+}
+
+// serverDefaults returns the decoded default of every field for the Kibana version and the rule type.
+// The defaults of the latest release are assumed when the version is unknown.
+func serverDefaults(version *helpers.Version, ruleType string, defaults []serverDefault) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, d := range defaults {
+		if d.version != nil && version != nil && !version.AtLeast(d.version) {
+			continue
+		}
+		if len(d.ruleTypes) > 0 && !slices.Contains(d.ruleTypes, ruleType) {
+			continue
+		}
+		value, err := decodeJSON(d.value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid default of %s: %s", d.field, err))
+		}
+		values[d.field] = value
+	}
+	return values
+}
+
+// pruneServerDefaults removes from the content read from Kibana the fields which the configured content does not
+// specify and which hold their server default, so that the defaults filled in by Kibana do not show as a diff.
+// The configured fields holding a default are restored when the response omitted them.
+func pruneServerDefaults(content string, configured string, version *helpers.Version, ruleType string, defaults []serverDefault) (string, error) {
+	fields, err := decodeJSON(content)
+	if err != nil {
+		return "", err
+	}
+	// The configured content is empty when the rule is imported or only described by typed attributes
+	var configuredFields interface{}
+	if configured != "" {
+		if configuredFields, err = decodeJSON(configured); err != nil {
+			return "", err
+		}
+	}
+
+	for field, value := range serverDefaults(version, ruleType, defaults) {
+		names := strings.Split(field, ".")
+		parents := names[:len(names)-1]
+		name := names[len(names)-1]
+		object := jsonObjectAt(fields, parents)
+		if object == nil {
+			continue
+		}
+		if configuredValue, ok := jsonObjectAt(configuredFields, parents)[name]; ok {
+			if _, ok := object[name]; !ok && reflect.DeepEqual(configuredValue, value) {
+				object[name] = configuredValue
+			}
+			continue
+		}
+		if v, ok := object[name]; ok && reflect.DeepEqual(v, value) {
+			delete(object, name)
+		}
+	}
+
+	output, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// decodeJSON decodes a JSON value, keeping its numbers as they are written
+func decodeJSON(value string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var decoded interface{}
+	err := decoder.Decode(&decoded)
+	return decoded, err
+}
+
+// jsonObjectAt returns the object found at the path of names in a decoded JSON value, or nil when there is none
+func jsonObjectAt(value interface{}, names []string) map[string]interface{} {
+	object, _ := value.(map[string]interface{})
+	for _, name := range names {
+		if object, _ = object[name].(map[string]interface{}); object == nil {
+			return nil
+		}
+	}
+	return object
+}
+
+// checkFieldRequirements adds an error for every field of the content which the Kibana version does not support.
+// Nothing is checked when the version is unknown.
+func checkFieldRequirements(diags *diag.Diagnostics, prefix string, version *helpers.Version, content string, requirements []fieldRequirement) {
+	if version == nil {
+		return
+	}
+	var fields map[string]interface{}
+	if err := helpers.ObjectFromJSON(content, &fields); err != nil {
+		// Invalid content is reported when it is parsed
+		return
+	}
+	for _, requirement := range requirements {
+		value, ok := fields[requirement.field]
+		if !ok || value == nil || version.AtLeast(requirement.version) {
+			continue
+		}
+		if requirement.value == "" {
+			diags.AddError(
+				prefix+" Unsupported Field",
+				fmt.Sprintf("The field '%s' requires Kibana %s or later, but the server runs Kibana %s. Remove it from the content or upgrade Kibana.",
+					requirement.field, requirement.version, version),
+			)
+		} else if value == requirement.value {
+			diags.AddError(
+				prefix+" Unsupported Field",
+				fmt.Sprintf("The %s '%s' requires Kibana %s or later, but the server runs Kibana %s.",
+					requirement.field, requirement.value, requirement.version, version),
+			)
+		}
+	}
+}
+
+// adaptDetectionRule encodes the fields of a rule in the format expected by the Kibana version
+func adaptDetectionRule(rule *transferobjects.DetectionRule, version *helpers.Version) {
+	if version == nil {
+		return
+	}
+	if rule.InvestigationFields != nil {
+		rule.InvestigationFields.SetLegacy(!version.AtLeast(investigationFieldsObjectVersion))
+	}
+}
@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"strings"
+	"terraform-provider-elastic-siem-detection/internal/helpers"
+	"terraform-provider-elastic-siem-detection/internal/provider/transferobjects"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestCheckFieldRequirements(t *testing.T) {
+	content := `{"type":"new_terms","name":"rule","investigation_fields":{"field_names":["host.name"]},"new_terms_fields":["user.name"]}`
+
+	var diags diag.Diagnostics
+	checkFieldRequirements(&diags, "[Test]", helpers.MustParseVersion("8.11.0"), content, detectionRuleRequirements)
+	if diags.HasError() {
+		t.Errorf("Unexpected diagnostics: %v", diags)
+	}
+
+	checkFieldRequirements(&diags, "[Test]", nil, content, detectionRuleRequirements)
+	if diags.HasError() {
+		t.Errorf("Expected no check when the version is unknown, got: %v", diags)
+	}
+
+	checkFieldRequirements(&diags, "[Test]", helpers.MustParseVersion("8.3.2"), content, detectionRuleRequirements)
+	if diags.ErrorsCount() != 3 {
+		t.Fatalf("Expected the rule type, investigation_fields and new_terms_fields to be rejected, got: %v", diags)
+	}
+	for _, d := range diags.Errors() {
+		if d.Summary() != "[Test] Unsupported Field" || !strings.Contains(d.Detail(), "8.3.2") {
+			t.Errorf("Unexpected diagnostic: %s: %s", d.Summary(), d.Detail())
+		}
+	}
+}
+
+func TestAdaptDetectionRule(t *testing.T) {
+	var rule *transferobjects.DetectionRule
+	if err := helpers.ObjectFromJSON(`{"name":"rule","investigation_fields":["host.name"]}`, &rule); err != nil {
+		t.Fatal(err)
+	}
+
+	adaptDetectionRule(rule, helpers.MustParseVersion("8.12.0"))
+	if json, _ := helpers.JSONToString(rule.InvestigationFields); json != `{"field_names":["host.name"]}` {
+		t.Errorf("Expected investigation_fields as an object, got: %s", json)
+	}
+
+	adaptDetectionRule(rule, helpers.MustParseVersion("8.10.4"))
+	if json, _ := helpers.JSONToString(rule.InvestigationFields); json != `["host.name"]` {
+		t.Errorf("Expected investigation_fields as an array, got: %s", json)
+	}
+}
+
+func TestPruneServerDefaults(t *testing.T) {
+	content := `{"name":"rule","enabled":true,"from":"now-6m","interval":"10m","max_signals":100,"language":"kuery","setup":"","tags":["a"]}`
+
+	pruned, err := pruneServerDefaults(content, `{"name":"rule","max_signals":100,"tags":["a"]}`, helpers.MustParseVersion("8.11.0"), "query", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"interval":"10m","max_signals":100,"name":"rule","tags":["a"]}`; pruned != expected {
+		t.Errorf("Expected %s, got %s", expected, pruned)
+	}
+
+	// Imported rules have no configured content
+	pruned, err = pruneServerDefaults(content, "", nil, "query", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"interval":"10m","name":"rule","tags":["a"]}`; pruned != expected {
+		t.Errorf("Expected %s, got %s", expected, pruned)
+	}
+
+	// setup is not filled in by Kibana before 8.3
+	pruned, err = pruneServerDefaults(content, "", helpers.MustParseVersion("8.2.0"), "query", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"interval":"10m","name":"rule","setup":"","tags":["a"]}`; pruned != expected {
+		t.Errorf("Expected %s, got %s", expected, pruned)
+	}
+
+	// The response leaves out empty arrays, the configured ones are kept
+	pruned, err = pruneServerDefaults(`{"name":"rule"}`, `{"name":"rule","references":[],"author":["me"]}`, nil, "query", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"name":"rule","references":[]}`; pruned != expected {
+		t.Errorf("Expected %s, got %s", expected, pruned)
+	}
+}
+
+func TestPruneServerDefaultsByRuleType(t *testing.T) {
+	// Only the rules written in KQL or Lucene default to kuery
+	content := `{"language":"kuery","name":"rule","type":"eql"}`
+	pruned, err := pruneServerDefaults(content, `{"name":"rule","type":"eql"}`, nil, "eql", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != content {
+		t.Errorf("Expected the language of the eql rule to be kept, got %s", pruned)
+	}
+
+	pruned, err = pruneServerDefaults(`{"name":"rule","type":"eql"}`, `{"name":"rule","type":"eql","language":"kuery"}`, nil, "eql", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"name":"rule","type":"eql"}`; pruned != expected {
+		t.Errorf("Expected kuery not to be restored as the language of the eql rule, got %s", pruned)
+	}
+
+	pruned, err = pruneServerDefaults(`{"language":"kuery","name":"rule","type":"threshold"}`, `{"name":"rule","type":"threshold"}`, nil, "threshold", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"name":"rule","type":"threshold"}`; pruned != expected {
+		t.Errorf("Expected %s, got %s", expected, pruned)
+	}
+}
+
+func TestPruneNestedServerDefaults(t *testing.T) {
+	content := `{"alert_suppression":{"group_by":["host.name"],"missing_fields_strategy":"suppress"},"name":"rule"}`
+
+	pruned, err := pruneServerDefaults(content, `{"alert_suppression":{"group_by":["host.name"]}}`, nil, "query", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"alert_suppression":{"group_by":["host.name"]},"name":"rule"}`; pruned != expected {
+		t.Errorf("Expected %s, got %s", expected, pruned)
+	}
+
+	pruned, err = pruneServerDefaults(content, `{"alert_suppression":{"missing_fields_strategy":"suppress"}}`, nil, "query", detectionRuleDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != content {
+		t.Errorf("Expected the configured strategy to be kept, got %s", pruned)
+	}
+}